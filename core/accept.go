@@ -0,0 +1,430 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AcceptList is the content of an Accept header, a list of media ranges
+// sorted by preference. See RFC 9110, Section 12.5.1.
+//
+//	type Input struct {
+//	    Accept core.AcceptList `in:"header=Accept"`
+//	}
+//
+// Multiple field lines of the header are combined before parsing. An empty
+// AcceptList is not encoded, i.e. no header will be set.
+type AcceptList []MediaRange
+
+// MediaRange is an element of the Accept header, e.g. "text/*;q=0.8".
+// Q is the weight in the range [0, 1]. The weight of a parsed media range
+// without the "q" parameter is 1. When building a MediaRange by hand, Q must
+// be set explicitly, since a weight of 0 means "not acceptable".
+type MediaRange struct {
+	Type    string // e.g. "text", or "*"
+	Subtype string // e.g. "html", or "*"
+	Params  []MediaParam
+	Q       float64
+}
+
+// MediaParam is a parameter of a media range, e.g. the "level=1" in
+// "text/html;level=1".
+type MediaParam struct {
+	Key   string
+	Value string
+}
+
+// Preference is an element of the Accept-Language or Accept-Encoding header,
+// e.g. "en-US;q=0.8" or "gzip;q=0.5". Q works the same as MediaRange.Q.
+type Preference struct {
+	Value string
+	Q     float64
+}
+
+// LanguagePreferences is the content of an Accept-Language header, a list of
+// language ranges sorted by preference. See RFC 9110, Section 12.5.4.
+type LanguagePreferences []Preference
+
+// EncodingPreferences is the content of an Accept-Encoding header, a list of
+// content codings sorted by preference. See RFC 9110, Section 12.5.3.
+type EncodingPreferences []Preference
+
+// Negotiate returns the offered media type which is the most preferred by the
+// client, e.g. Negotiate("application/json", "text/html"). Ties are broken by
+// the order of the offers. Returns an empty string when none of the offers is
+// acceptable. An empty AcceptList accepts everything, so the first offer is
+// returned.
+func (l AcceptList) Negotiate(offers ...string) string {
+	return negotiate(len(l) == 0, offers, func(offer string) float64 {
+		mr, err := parseMediaRange(offer)
+		if err != nil {
+			return 0
+		}
+		best, q := -1, 0.0
+		for _, r := range l {
+			if s := r.specificity(); s > best && r.matches(mr) {
+				best, q = s, r.Q
+			}
+		}
+		return q
+	})
+}
+
+// Negotiate returns the offered language tag which is the most preferred by
+// the client, e.g. Negotiate("en", "zh-CN"). The language ranges are matched
+// with the "Basic Filtering" scheme of RFC 4647, i.e. "en" matches "en-US".
+// See AcceptList.Negotiate for the rest of the rules.
+func (l LanguagePreferences) Negotiate(offers ...string) string {
+	return negotiate(len(l) == 0, offers, func(offer string) float64 {
+		best, q := -1, 0.0
+		for _, p := range l {
+			if len(p.Value) > best && matchLanguageRange(p.Value, offer) {
+				best, q = len(p.Value), p.Q
+			}
+		}
+		return q
+	})
+}
+
+// Negotiate returns the offered content coding which is the most preferred by
+// the client, e.g. Negotiate("br", "gzip", "identity"). The "identity" coding
+// is acceptable with the lowest weight unless it is excluded explicitly, e.g.
+// by "identity;q=0" or "*;q=0". See AcceptList.Negotiate for the rest of the
+// rules.
+func (l EncodingPreferences) Negotiate(offers ...string) string {
+	return negotiate(len(l) == 0, offers, func(offer string) float64 {
+		matched, q := false, 0.0
+		for _, p := range l {
+			if strings.EqualFold(p.Value, offer) {
+				return p.Q
+			}
+			if p.Value == "*" {
+				matched, q = true, p.Q
+			}
+		}
+		if !matched && strings.EqualFold(offer, "identity") {
+			return 0.001
+		}
+		return q
+	})
+}
+
+// negotiate returns the offer with the highest weight. The first offer wins
+// when acceptAll is true.
+func negotiate(acceptAll bool, offers []string, weigh func(offer string) float64) string {
+	if acceptAll {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+		return ""
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := weigh(offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+func (r MediaRange) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.Type)
+	sb.WriteByte('/')
+	sb.WriteString(r.Subtype)
+	for _, p := range r.Params {
+		sb.WriteByte(';')
+		sb.WriteString(p.Key)
+		sb.WriteByte('=')
+		sb.WriteString(quoteIfNeeded(p.Value))
+	}
+	sb.WriteString(formatWeight(r.Q))
+	return sb.String()
+}
+
+// specificity ranks the media ranges, the more specific one overrides the
+// less specific one, e.g. "text/html;level=1" > "text/html" > "text/*" > "*/*".
+func (r MediaRange) specificity() int {
+	switch {
+	case r.Type == "*":
+		return 0
+	case r.Subtype == "*":
+		return 1
+	default:
+		return 2 + len(r.Params)
+	}
+}
+
+// matches reports whether the media type mt falls into the media range r.
+func (r MediaRange) matches(mt MediaRange) bool {
+	if r.Type != "*" && r.Type != mt.Type {
+		return false
+	}
+	if r.Subtype != "*" && r.Subtype != mt.Subtype {
+		return false
+	}
+	for _, p := range r.Params {
+		if !containsMediaParam(mt.Params, p) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsMediaParam(params []MediaParam, p MediaParam) bool {
+	for _, q := range params {
+		if q.Key == p.Key && strings.EqualFold(q.Value, p.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Preference) String() string {
+	return p.Value + formatWeight(p.Q)
+}
+
+func (l AcceptList) ToString() (string, error) {
+	return joinPreferences(l), nil
+}
+
+func (l *AcceptList) FromString(s string) error {
+	return l.FromStringSlice([]string{s})
+}
+
+func (l AcceptList) ToStringSlice() ([]string, error) {
+	return preferencesToStringSlice(l)
+}
+
+func (l *AcceptList) FromStringSlice(values []string) error {
+	list, err := parsePreferences(values, parseMediaRange)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Q != list[j].Q {
+			return list[i].Q > list[j].Q
+		}
+		return list[i].specificity() > list[j].specificity()
+	})
+	*l = list
+	return nil
+}
+
+func (l LanguagePreferences) ToString() (string, error) {
+	return joinPreferences(l), nil
+}
+
+func (l *LanguagePreferences) FromString(s string) error {
+	return l.FromStringSlice([]string{s})
+}
+
+func (l LanguagePreferences) ToStringSlice() ([]string, error) {
+	return preferencesToStringSlice(l)
+}
+
+func (l *LanguagePreferences) FromStringSlice(values []string) error {
+	list, err := parsePreferences(values, parsePreference)
+	if err != nil {
+		return err
+	}
+	sortPreferences(list)
+	*l = list
+	return nil
+}
+
+func (l EncodingPreferences) ToString() (string, error) {
+	return joinPreferences(l), nil
+}
+
+func (l *EncodingPreferences) FromString(s string) error {
+	return l.FromStringSlice([]string{s})
+}
+
+func (l EncodingPreferences) ToStringSlice() ([]string, error) {
+	return preferencesToStringSlice(l)
+}
+
+func (l *EncodingPreferences) FromStringSlice(values []string) error {
+	list, err := parsePreferences(values, parsePreference)
+	if err != nil {
+		return err
+	}
+	sortPreferences(list)
+	*l = list
+	return nil
+}
+
+func sortPreferences(list []Preference) {
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Q > list[j].Q
+	})
+}
+
+func joinPreferences[T fmt.Stringer](list []T) string {
+	elements := make([]string, len(list))
+	for i, element := range list {
+		elements[i] = element.String()
+	}
+	return strings.Join(elements, ", ")
+}
+
+func preferencesToStringSlice[T fmt.Stringer](list []T) ([]string, error) {
+	if len(list) == 0 {
+		return []string{}, nil // an empty list is not encoded
+	}
+	return []string{joinPreferences(list)}, nil
+}
+
+// parsePreferences parses the comma-separated elements of the header values.
+// Empty elements are ignored, see RFC 9110, Section 5.6.1.
+func parsePreferences[T any](values []string, parse func(string) (T, error)) ([]T, error) {
+	var list []T
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			if strings.TrimSpace(element) == "" {
+				continue
+			}
+			item, err := parse(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
+
+// parseMediaRange parses a media range with an optional weight, e.g.
+// "text/html;level=1;q=0.8".
+func parseMediaRange(s string) (MediaRange, error) {
+	parts := splitQuoted(s, ';')
+	typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(parts[0])), "/")
+	if !ok || !isToken(typ) || !isToken(subtype) || (typ == "*" && subtype != "*") {
+		return MediaRange{}, fmt.Errorf("invalid media range %q", strings.TrimSpace(s))
+	}
+	r := MediaRange{Type: typ, Subtype: subtype, Q: 1}
+	for _, part := range parts[1:] {
+		key, value, err := parseHeaderParam(part)
+		if err != nil {
+			return MediaRange{}, err
+		}
+		if key == "q" {
+			if r.Q, err = parseWeight(value); err != nil {
+				return MediaRange{}, err
+			}
+			continue
+		}
+		r.Params = append(r.Params, MediaParam{Key: key, Value: value})
+	}
+	return r, nil
+}
+
+// parsePreference parses a value with an optional weight, e.g. "en;q=0.8".
+// Parameters other than the weight are not allowed.
+func parsePreference(s string) (Preference, error) {
+	parts := splitQuoted(s, ';')
+	p := Preference{Value: strings.TrimSpace(parts[0]), Q: 1}
+	if !isToken(p.Value) {
+		return Preference{}, fmt.Errorf("invalid preference %q", strings.TrimSpace(s))
+	}
+	for _, part := range parts[1:] {
+		key, value, err := parseHeaderParam(part)
+		if err != nil {
+			return Preference{}, err
+		}
+		if key != "q" {
+			return Preference{}, fmt.Errorf("unexpected parameter %q", key)
+		}
+		if p.Q, err = parseWeight(value); err != nil {
+			return Preference{}, err
+		}
+	}
+	return p, nil
+}
+
+// parseHeaderParam parses a "key=value" pair. The key is case-insensitive and
+// is returned in lower case. The value can be a token or a quoted-string.
+func parseHeaderParam(s string) (key, value string, err error) {
+	key, value, found := strings.Cut(strings.TrimSpace(s), "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	if !found || !isToken(key) || value == "" {
+		return "", "", fmt.Errorf("invalid parameter %q", strings.TrimSpace(s))
+	}
+	return key, unquote(value), nil
+}
+
+// parseWeight parses a qvalue, see RFC 9110, Section 12.4.2.
+//
+//	qvalue = ( "0" [ "." 0*3DIGIT ] ) / ( "1" [ "." 0*3("0") ] )
+func parseWeight(s string) (float64, error) {
+	integer, fraction, _ := strings.Cut(s, ".")
+	if (integer != "0" && integer != "1") || len(fraction) > 3 ||
+		strings.Trim(fraction, "0123456789") != "" ||
+		(integer == "1" && strings.Trim(fraction, "0") != "") {
+		return 0, fmt.Errorf("invalid weight %q", s)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// formatWeight returns the ";q=" suffix of the weight q, which is rounded to
+// three decimal places, see RFC 9110, Section 12.4.2. An empty string is
+// returned when q is 1, which is the default weight.
+func formatWeight(q float64) string {
+	if q < 0 {
+		q = 0
+	}
+	s := strconv.FormatFloat(q, 'f', 3, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if q >= 1 || s == "1" {
+		return ""
+	}
+	return ";q=" + s
+}
+
+// matchLanguageRange reports whether the language tag matches the language
+// range, see RFC 4647, Section 3.3.1.
+func matchLanguageRange(languageRange, tag string) bool {
+	if languageRange == "*" {
+		return true
+	}
+	if len(tag) < len(languageRange) || !strings.EqualFold(tag[:len(languageRange)], languageRange) {
+		return false
+	}
+	return len(tag) == len(languageRange) || tag[len(languageRange)] == '-'
+}
+
+// isToken reports whether s is a token, see RFC 9110, Section 5.6.2.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteIfNeeded returns s as a quoted-string if it is not a token.
+func quoteIfNeeded(s string) string {
+	if isToken(s) {
+		return s
+	}
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(s[i])
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptList(t *testing.T) {
+	var list AcceptList
+	assert.NoError(t, list.FromStringSlice([]string{
+		`text/*;q=0.3, text/html;q=0.7, text/html;level=1`,
+		`text/html;level=2;q=0.4, */*;q=0.5, application/x-custom;name="a b"`,
+	}))
+	assert.Equal(t, AcceptList{
+		{Type: "text", Subtype: "html", Params: []MediaParam{{"level", "1"}}, Q: 1},
+		{Type: "application", Subtype: "x-custom", Params: []MediaParam{{"name", "a b"}}, Q: 1},
+		{Type: "text", Subtype: "html", Q: 0.7},
+		{Type: "*", Subtype: "*", Q: 0.5},
+		{Type: "text", Subtype: "html", Params: []MediaParam{{"level", "2"}}, Q: 0.4},
+		{Type: "text", Subtype: "*", Q: 0.3},
+	}, list)
+
+	s, err := list.ToString()
+	assert.NoError(t, err)
+	assert.Equal(t, `text/html;level=1, application/x-custom;name="a b", text/html;q=0.7, */*;q=0.5, text/html;level=2;q=0.4, text/*;q=0.3`, s)
+
+	// RFC 9110, Section 12.5.1.
+	assert.Equal(t, "text/html;level=1", list.Negotiate("text/html;level=1"))
+	assert.Equal(t, "text/html", list.Negotiate("text/plain", "text/html"))
+	assert.Equal(t, "image/jpeg", list.Negotiate("text/plain", "image/jpeg"))
+	assert.Equal(t, "text/html;level=3", list.Negotiate("text/html;level=2", "text/html;level=3"))
+	assert.Equal(t, "", list.Negotiate("invalid"))
+
+	assert.NoError(t, list.FromString("application/json, */*;q=0"))
+	assert.Equal(t, "application/json", list.Negotiate("text/html", "application/json"))
+	assert.Equal(t, "", list.Negotiate("text/html"))
+
+	assert.NoError(t, list.FromString(""))
+	assert.Empty(t, list)
+	assert.Equal(t, "text/html", list.Negotiate("text/html", "application/json"))
+	assert.Equal(t, "", list.Negotiate())
+	values, err := list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	for _, invalid := range []string{"text", "*/html", "text/html;q=2", "text/html;q=0.1234", "text/html;level", "text/html;q=abc"} {
+		assert.Error(t, list.FromString(invalid), invalid)
+	}
+}
+
+func TestFormatWeight(t *testing.T) {
+	list := AcceptList{
+		{Type: "text", Subtype: "html", Q: 0.1234},
+		{Type: "text", Subtype: "plain", Q: 0.5},
+		{Type: "*", Subtype: "*", Q: 0.0001},
+	}
+	s, err := list.ToString()
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html;q=0.123, text/plain;q=0.5, */*;q=0", s)
+
+	prefs := LanguagePreferences{{"en", 0.9996}, {"fr", 0.25}}
+	s, err = prefs.ToString()
+	assert.NoError(t, err)
+	assert.Equal(t, "en, fr;q=0.25", s)
+}
+
+func TestLanguagePreferences(t *testing.T) {
+	var prefs LanguagePreferences
+	assert.NoError(t, prefs.FromString("da, en-gb;q=0.8, en;q=0.7, *;q=0.1"))
+	assert.Equal(t, LanguagePreferences{
+		{"da", 1}, {"en-gb", 0.8}, {"en", 0.7}, {"*", 0.1},
+	}, prefs)
+
+	assert.Equal(t, "en-GB", prefs.Negotiate("en-US", "en-GB"))
+	assert.Equal(t, "en-US", prefs.Negotiate("en-US", "zh-CN"))
+	assert.Equal(t, "zh-CN", prefs.Negotiate("zh-CN"))
+	assert.Equal(t, "da", prefs.Negotiate("fr", "en", "da"))
+
+	assert.NoError(t, prefs.FromString("en"))
+	assert.Equal(t, "", prefs.Negotiate("eng", "fr"))
+
+	assert.Error(t, prefs.FromString("en;level=1"))
+	assert.Error(t, prefs.FromString("en us"))
+}
+
+func TestEncodingPreferences(t *testing.T) {
+	var prefs EncodingPreferences
+	assert.NoError(t, prefs.FromString("gzip;q=0.5, br"))
+	assert.Equal(t, EncodingPreferences{{"br", 1}, {"gzip", 0.5}}, prefs)
+	assert.Equal(t, "br", prefs.Negotiate("gzip", "br", "identity"))
+	assert.Equal(t, "gzip", prefs.Negotiate("gzip", "identity"))
+	assert.Equal(t, "identity", prefs.Negotiate("deflate", "identity"))
+
+	assert.NoError(t, prefs.FromString("gzip, *;q=0"))
+	assert.Equal(t, "", prefs.Negotiate("deflate", "identity"))
+
+	assert.NoError(t, prefs.FromString("gzip, identity;q=0"))
+	assert.Equal(t, "", prefs.Negotiate("identity"))
+}
+
+func TestDirectiveHeader_ContentNegotiation(t *testing.T) {
+	type Input struct {
+		Accept         AcceptList          `in:"header=Accept"`
+		AcceptLanguage LanguagePreferences `in:"header=Accept-Language"`
+		AcceptEncoding EncodingPreferences `in:"header=Accept-Encoding"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Add("Accept", "text/html;q=0.9")
+	r.Header.Add("Accept", "application/json")
+	r.Header.Set("Accept-Language", "en;q=0.5, zh-CN")
+	expected := &Input{
+		Accept: AcceptList{
+			{Type: "application", Subtype: "json", Q: 1},
+			{Type: "text", Subtype: "html", Q: 0.9},
+		},
+		AcceptLanguage: LanguagePreferences{{"zh-CN", 1}, {"en", 0.5}},
+	}
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+	assert.Equal(t, "application/json", got.(*Input).Accept.Negotiate("text/html", "application/json"))
+
+	req, err := co.NewRequest("GET", "/", &Input{
+		Accept:         AcceptList{{Type: "application", Subtype: "json", Q: 1}, {Type: "*", Subtype: "*", Q: 0.1}},
+		AcceptLanguage: LanguagePreferences{{"en-US", 1}, {"en", 0.5}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json, */*;q=0.1", req.Header.Get("Accept"))
+	assert.Equal(t, "en-US, en;q=0.5", req.Header.Get("Accept-Language"))
+	assert.Empty(t, req.Header.Values("Accept-Encoding"))
+
+	r.Header.Set("Accept", "text/html;q=high")
+	_, err = co.Decode(r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+// directive: "auth"
+// https://ggicci.github.io/httpin/directives/auth
+
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ggicci/httpin/internal"
+	"github.com/ggicci/owl"
+)
+
+var ErrMalformedCredentials = errors.New("malformed credentials")
+
+// BasicCredentials are the credentials of the "Basic" HTTP authentication
+// scheme, see RFC 7617.
+type BasicCredentials struct {
+	Username string
+	Password string
+}
+
+// DirectiveAuth implements the "auth" executor who extracts the credentials
+// from the Authorization header. The argument is the authentication scheme,
+// "basic" or "bearer", e.g.
+//
+//	type Input struct {
+//		Token string                 `in:"auth=bearer"`
+//		Login *core.BasicCredentials `in:"auth=basic"`
+//	}
+//
+// The field of the "basic" scheme must be of type BasicCredentials or
+// *BasicCredentials. The token of the "bearer" scheme is decoded by the coders,
+// the same as the values of the "header" directive. The field is left
+// untouched when the Authorization header is absent or uses another scheme.
+//
+// The credentials are never included in the errors, i.e. the Value of the
+// InvalidFieldError is always nil.
+type DirectiveAuth struct{}
+
+func (*DirectiveAuth) Decode(rtm *DirectiveRuntime) error {
+	if rtm.IsFieldSet() {
+		return nil // skip when already extracted by former directives
+	}
+
+	scheme := rtm.Directive.Argv[0]
+	header := rtm.GetRequest().Header.Get("Authorization")
+	if header == "" {
+		return nil
+	}
+	credentials, ok, err := parseAuthorization(header, scheme)
+	if err == nil && ok {
+		err = decodeCredentials(rtm, scheme, credentials)
+	}
+	if err != nil {
+		return &fieldError{"Authorization", nil, err}
+	}
+	if ok {
+		rtm.MarkFieldSet(true)
+	}
+	return nil
+}
+
+// Encode sets the Authorization header. Zero values are skipped.
+func (*DirectiveAuth) Encode(rtm *DirectiveRuntime) error {
+	if rtm.IsFieldSet() {
+		return nil // skip when already encoded by former directives
+	}
+
+	var header string
+	switch rtm.Directive.Argv[0] {
+	case "basic":
+		if internal.IsNil(rtm.Value) {
+			return nil
+		}
+		cred := reflect.Indirect(rtm.Value).Interface().(BasicCredentials)
+		if cred == (BasicCredentials{}) {
+			return nil
+		}
+		if strings.Contains(cred.Username, ":") {
+			return fmt.Errorf("%w: username of Basic credentials cannot contain a colon", ErrMalformedCredentials)
+		}
+		header = "Basic " + base64.StdEncoding.EncodeToString([]byte(cred.Username+":"+cred.Password))
+	case "bearer":
+		if IsPatchField(rtm.Value.Type()) && !rtm.Value.FieldByName("Valid").Bool() {
+			return nil // skip when the patch field is not set
+		}
+		var adapt AnyStringableAdaptor
+		if coder := rtm.GetCustomCoder(); coder != nil {
+			adapt = coder.Adapt
+		}
+		encoder, err := NewStringSlicable(rtm.Value, adapt)
+		if err != nil {
+			return err
+		}
+		values, err := encoder.ToStringSlice()
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 || values[0] == "" {
+			return nil
+		}
+		header = "Bearer " + values[0]
+	}
+
+	rtm.GetRequestBuilder().SetHeader("Authorization", []string{header})
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+func decodeCredentials(rtm *DirectiveRuntime, scheme, credentials string) error {
+	if scheme == "basic" {
+		cred, err := parseBasicCredentials(credentials)
+		if err != nil {
+			return err
+		}
+		fv := rtm.Value.Elem()
+		if fv.Kind() == reflect.Pointer {
+			fv = createInstanceIfNil(fv).Elem()
+		}
+		fv.Set(reflect.ValueOf(cred))
+		return nil
+	}
+
+	if !isToken68(credentials) {
+		return fmt.Errorf("%w: invalid Bearer token", ErrMalformedCredentials)
+	}
+	var adapt AnyStringableAdaptor
+	if coder := rtm.GetCustomCoder(); coder != nil {
+		adapt = coder.Adapt
+	}
+	decoder, err := NewStringSlicable(rtm.Value.Elem(), adapt)
+	if err != nil {
+		return err
+	}
+	if err := decoder.FromStringSlice([]string{credentials}); err != nil {
+		// The underlying error may contain the token.
+		return fmt.Errorf("cannot decode Bearer token into type %q", rtm.Value.Elem().Type())
+	}
+	return nil
+}
+
+// parseAuthorization returns the credentials of the Authorization header.
+// Returns false if the header uses another scheme. See RFC 9110, Section 11.4.
+//
+//	credentials = auth-scheme [ 1*SP ( token68 / #auth-param ) ]
+func parseAuthorization(header, scheme string) (string, bool, error) {
+	authScheme, credentials, _ := strings.Cut(strings.TrimSpace(header), " ")
+	if !isToken(authScheme) {
+		return "", false, fmt.Errorf("%w: invalid authentication scheme", ErrMalformedCredentials)
+	}
+	if !strings.EqualFold(authScheme, scheme) {
+		return "", false, nil
+	}
+	credentials = strings.TrimLeft(credentials, " ")
+	if credentials == "" {
+		return "", false, fmt.Errorf("%w: missing credentials of %s scheme", ErrMalformedCredentials, authScheme)
+	}
+	return credentials, true, nil
+}
+
+// parseBasicCredentials decodes the base64 encoded "user-id:password" pair.
+func parseBasicCredentials(credentials string) (BasicCredentials, error) {
+	decoded, err := base64.StdEncoding.DecodeString(credentials)
+	if err != nil {
+		return BasicCredentials{}, fmt.Errorf("%w: invalid base64 encoding of Basic credentials", ErrMalformedCredentials)
+	}
+	username, password, found := strings.Cut(string(decoded), ":")
+	if !found {
+		return BasicCredentials{}, fmt.Errorf("%w: missing colon in Basic credentials", ErrMalformedCredentials)
+	}
+	return BasicCredentials{Username: username, Password: password}, nil
+}
+
+// isToken68 reports whether s is a token68, see RFC 9110, Section 11.2.
+//
+//	token68 = 1*( ALPHA / DIGIT / "-" / "." / "_" / "~" / "+" / "/" ) *"="
+func isToken68(s string) bool {
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("-._~+/", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+var basicCredentialsType = internal.TypeOf[BasicCredentials]()
+
+// validateAuthDirective ensures that the scheme of the "auth" directive is
+// supported, and the field type fits the scheme.
+func validateAuthDirective(r *owl.Resolver) error {
+	d := r.GetDirective("auth")
+	if d == nil {
+		return nil
+	}
+	if len(d.Argv) != 1 {
+		return errors.New("directive auth: expect exactly one scheme, basic or bearer")
+	}
+	switch d.Argv[0] {
+	case "basic":
+		if r.Type != basicCredentialsType && r.Type != reflect.PointerTo(basicCredentialsType) {
+			return fmt.Errorf("directive auth: %w: scheme basic requires type %q, got %q",
+				ErrTypeMismatch, basicCredentialsType, r.Type)
+		}
+	case "bearer":
+		if isFileType(r.Type) {
+			return errors.New("directive auth: cannot be used on a file type field")
+		}
+	default:
+		return fmt.Errorf("directive auth: unknown scheme %q", d.Argv[0])
+	}
+	return nil
+}
@@ -0,0 +1,634 @@
+package core
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The binary body formats, i.e. MessagePack and CBOR, share the same data
+// model. A Go value is converted to a generic value before being written, and
+// a generic value is read before being converted to a Go value. The generic
+// values are:
+//
+//   - nil, bool, int64, uint64, float32, float64, string, []byte, time.Time;
+//   - []any: arrays;
+//   - genericMap: maps and structs, the order of the entries is kept.
+//
+// The struct fields are named by the tag of the format, e.g. `msgpack:"name"`,
+// then by the "json" tag, and lastly by the field name. The "omitempty" option
+// is supported as the same as encoding/json. A patch.Field is encoded as its
+// Value or nil if not Valid, and it's Valid after decoding a non-nil value.
+
+type genericMap []genericEntry
+
+type genericEntry struct {
+	Key   any
+	Value any
+}
+
+// maxGenericDepth limits the nesting of the decoded values.
+const maxGenericDepth = 1000
+
+var errGenericTooDeep = errors.New("exceeded max depth")
+
+// toGeneric converts a Go value to a generic value.
+func toGeneric(rv reflect.Value, tag string) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if rv.Type() == timeType {
+		return rv.Interface().(time.Time), nil
+	}
+	if IsPatchField(rv.Type()) {
+		if !rv.FieldByName("Valid").Bool() {
+			return nil, nil
+		}
+		return toGeneric(rv.FieldByName("Value"), tag)
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return toGeneric(rv.Elem(), tag)
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint(), nil
+	case reflect.Float32:
+		return float32(rv.Float()), nil
+	case reflect.Float64:
+		return rv.Float(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		return sliceToGeneric(rv, tag)
+	case reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return b, nil
+		}
+		return sliceToGeneric(rv, tag)
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return mapToGeneric(rv, tag)
+	case reflect.Struct:
+		return structToGeneric(rv, tag)
+	}
+	return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+}
+
+func sliceToGeneric(rv reflect.Value, tag string) (any, error) {
+	elems := make([]any, rv.Len())
+	for i := range elems {
+		elem, err := toGeneric(rv.Index(i), tag)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = elem
+	}
+	return elems, nil
+}
+
+func mapToGeneric(rv reflect.Value, tag string) (any, error) {
+	keys := rv.MapKeys()
+	// Sort the keys to make the output deterministic.
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	m := make(genericMap, len(keys))
+	for i, key := range keys {
+		k, err := toGeneric(key, tag)
+		if err != nil {
+			return nil, err
+		}
+		v, err := toGeneric(rv.MapIndex(key), tag)
+		if err != nil {
+			return nil, err
+		}
+		m[i] = genericEntry{k, v}
+	}
+	return m, nil
+}
+
+func structToGeneric(rv reflect.Value, tag string) (any, error) {
+	fields := cachedGenericFields(rv.Type(), tag)
+	m := make(genericMap, 0, len(fields))
+	for _, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		if f.omitEmpty && isEmptyGenericValue(fv) {
+			continue
+		}
+		v, err := toGeneric(fv, tag)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.name, err)
+		}
+		m = append(m, genericEntry{f.name, v})
+	}
+	return m, nil
+}
+
+func isEmptyGenericValue(rv reflect.Value) bool {
+	if IsPatchField(rv.Type()) {
+		return !rv.FieldByName("Valid").Bool()
+	}
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return rv.IsZero()
+	}
+	return false
+}
+
+// fromGeneric converts a generic value to a Go value. rv must be settable.
+func fromGeneric(v any, rv reflect.Value, tag string) error {
+	if rv.Type() == timeType {
+		return timeFromGeneric(v, rv)
+	}
+	if IsPatchField(rv.Type()) {
+		if v == nil {
+			return nil
+		}
+		if err := fromGeneric(v, rv.FieldByName("Value"), tag); err != nil {
+			return err
+		}
+		rv.FieldByName("Valid").SetBool(true)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if v == nil {
+			rv.SetZero()
+			return nil
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return fromGeneric(v, rv.Elem(), tag)
+	case reflect.Interface:
+		if v == nil {
+			rv.SetZero()
+			return nil
+		}
+		if rv.NumMethod() > 0 {
+			return fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+		}
+		natural, err := naturalValue(v)
+		if err != nil {
+			return err
+		}
+		rv.Set(reflect.ValueOf(natural))
+		return nil
+	case reflect.Map, reflect.Slice:
+		if v == nil {
+			rv.SetZero()
+			return nil
+		}
+	}
+	if v == nil {
+		return nil // leave the value untouched, as encoding/json does
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			rv.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := genericInt(v); ok {
+			if rv.OverflowInt(n) {
+				return fmt.Errorf("value %v overflows type %v", v, rv.Type())
+			}
+			rv.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := genericUint(v); ok {
+			if rv.OverflowUint(n) {
+				return fmt.Errorf("value %v overflows type %v", v, rv.Type())
+			}
+			rv.SetUint(n)
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := genericFloat(v); ok {
+			rv.SetFloat(f)
+			return nil
+		}
+	case reflect.String:
+		switch s := v.(type) {
+		case string:
+			rv.SetString(s)
+			return nil
+		case []byte:
+			rv.SetString(string(s))
+			return nil
+		}
+	case reflect.Slice:
+		return sliceFromGeneric(v, rv, tag)
+	case reflect.Array:
+		return arrayFromGeneric(v, rv, tag)
+	case reflect.Map:
+		if m, ok := v.(genericMap); ok {
+			return mapFromGeneric(m, rv, tag)
+		}
+	case reflect.Struct:
+		if m, ok := v.(genericMap); ok {
+			return structFromGeneric(m, rv, tag)
+		}
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+	}
+	return errCannotDecode(v, rv.Type())
+}
+
+func sliceFromGeneric(v any, rv reflect.Value, tag string) error {
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		switch b := v.(type) {
+		case []byte:
+			rv.SetBytes(append([]byte(nil), b...))
+			return nil
+		case string:
+			decoded, err := base64.StdEncoding.DecodeString(b) // as encoding/json does
+			if err != nil {
+				return fmt.Errorf("cannot decode string into type %v: %w", rv.Type(), err)
+			}
+			rv.SetBytes(decoded)
+			return nil
+		}
+	}
+	elems, ok := v.([]any)
+	if !ok {
+		return errCannotDecode(v, rv.Type())
+	}
+	slice := reflect.MakeSlice(rv.Type(), len(elems), len(elems))
+	for i, elem := range elems {
+		if err := fromGeneric(elem, slice.Index(i), tag); err != nil {
+			return fmt.Errorf("element at index %d: %w", i, err)
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
+
+func arrayFromGeneric(v any, rv reflect.Value, tag string) error {
+	if b, ok := v.([]byte); ok && rv.Type().Elem().Kind() == reflect.Uint8 {
+		rv.SetZero()
+		reflect.Copy(rv, reflect.ValueOf(b))
+		return nil
+	}
+	elems, ok := v.([]any)
+	if !ok {
+		return errCannotDecode(v, rv.Type())
+	}
+	rv.SetZero()
+	for i := 0; i < len(elems) && i < rv.Len(); i++ {
+		if err := fromGeneric(elems[i], rv.Index(i), tag); err != nil {
+			return fmt.Errorf("element at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func mapFromGeneric(m genericMap, rv reflect.Value, tag string) error {
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(rv.Type(), len(m)))
+	}
+	keyType, valueType := rv.Type().Key(), rv.Type().Elem()
+	for _, entry := range m {
+		key := reflect.New(keyType).Elem()
+		if err := fromGeneric(entry.Key, key, tag); err != nil {
+			return fmt.Errorf("map key %v: %w", entry.Key, err)
+		}
+		value := reflect.New(valueType).Elem()
+		if err := fromGeneric(entry.Value, value, tag); err != nil {
+			return fmt.Errorf("map key %v: %w", entry.Key, err)
+		}
+		rv.SetMapIndex(key, value)
+	}
+	return nil
+}
+
+func structFromGeneric(m genericMap, rv reflect.Value, tag string) error {
+	fields := cachedGenericFields(rv.Type(), tag)
+	for _, entry := range m {
+		var name string
+		switch key := entry.Key.(type) {
+		case string:
+			name = key
+		case []byte:
+			name = string(key)
+		default:
+			continue
+		}
+		f := fields.lookup(name)
+		if f == nil {
+			continue // unknown fields are ignored
+		}
+		if err := fromGeneric(entry.Value, rv.FieldByIndex(f.index), tag); err != nil {
+			return fmt.Errorf("field %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func timeFromGeneric(v any, rv reflect.Value) error {
+	var t time.Time
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		t = value
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", value)
+		}
+		t = parsed
+	default:
+		if sec, ok := genericInt(v); ok {
+			t = time.Unix(sec, 0)
+		} else if f, ok := genericFloat(v); ok {
+			sec, frac := math.Modf(f)
+			t = time.Unix(int64(sec), int64(frac*1e9))
+		} else {
+			return errCannotDecode(v, rv.Type())
+		}
+	}
+	rv.Set(reflect.ValueOf(t.UTC()))
+	return nil
+}
+
+func genericInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= math.MaxInt64
+	case float32:
+		return genericInt(float64(n))
+	case float64:
+		if n == math.Trunc(n) && n >= math.MinInt64 && n < math.MaxInt64 {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func genericUint(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return uint64(n), n >= 0
+	case uint64:
+		return n, true
+	case float32:
+		return genericUint(float64(n))
+	case float64:
+		if n == math.Trunc(n) && n >= 0 && n < math.MaxUint64 {
+			return uint64(n), true
+		}
+	}
+	return 0, false
+}
+
+func genericFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func genericKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case int64, uint64:
+		return "integer"
+	case float32, float64:
+		return "float"
+	case string:
+		return "string"
+	case []byte:
+		return "bytes"
+	case time.Time:
+		return "timestamp"
+	case []any:
+		return "array"
+	case genericMap:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// genericTypeError is reported when the kind of the generic value doesn't
+// match the type, which is an error of ErrTypeMismatch.
+type genericTypeError struct {
+	kind string
+	typ  reflect.Type
+}
+
+func errCannotDecode(v any, typ reflect.Type) error {
+	return &genericTypeError{genericKind(v), typ}
+}
+
+func (e *genericTypeError) Error() string {
+	return fmt.Sprintf("cannot decode %s into type %v", e.kind, e.typ)
+}
+
+func (e *genericTypeError) Unwrap() error {
+	return ErrTypeMismatch
+}
+
+// naturalValue converts a generic value to the value of an interface{}. The
+// maps are converted to map[string]any, or map[any]any if not all the keys
+// are strings. The integers are int64 unless they overflow.
+func naturalValue(v any) (any, error) {
+	switch value := v.(type) {
+	case uint64:
+		if value <= math.MaxInt64 {
+			return int64(value), nil
+		}
+	case []any:
+		elems := make([]any, len(value))
+		for i, elem := range value {
+			natural, err := naturalValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = natural
+		}
+		return elems, nil
+	case genericMap:
+		return naturalMap(value)
+	}
+	return v, nil
+}
+
+func naturalMap(m genericMap) (any, error) {
+	stringKeys := true
+	for _, entry := range m {
+		if _, ok := entry.Key.(string); !ok {
+			stringKeys = false
+			break
+		}
+	}
+	if stringKeys {
+		result := make(map[string]any, len(m))
+		for _, entry := range m {
+			value, err := naturalValue(entry.Value)
+			if err != nil {
+				return nil, err
+			}
+			result[entry.Key.(string)] = value
+		}
+		return result, nil
+	}
+
+	result := make(map[any]any, len(m))
+	for _, entry := range m {
+		key, err := naturalValue(entry.Key)
+		if err != nil {
+			return nil, err
+		}
+		switch k := key.(type) {
+		case []byte:
+			key = string(k)
+		case []any, map[string]any, map[any]any:
+			return nil, fmt.Errorf("unhashable map key of %s", genericKind(entry.Key))
+		}
+		value, err := naturalValue(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+type genericField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+}
+
+type genericFields []*genericField
+
+// lookup finds the field by name, the exact match is preferred over the
+// case-insensitive match, as encoding/json does.
+func (fields genericFields) lookup(name string) *genericField {
+	var folded *genericField
+	for _, f := range fields {
+		if f.name == name {
+			return f
+		}
+		if folded == nil && strings.EqualFold(f.name, name) {
+			folded = f
+		}
+	}
+	return folded
+}
+
+type genericFieldsKey struct {
+	typ reflect.Type
+	tag string
+}
+
+var genericFieldsCache sync.Map // map[genericFieldsKey]genericFields
+
+func cachedGenericFields(t reflect.Type, tag string) genericFields {
+	key := genericFieldsKey{t, tag}
+	if fields, ok := genericFieldsCache.Load(key); ok {
+		return fields.(genericFields)
+	}
+	fields := collectGenericFields(t, tag, nil, make(map[string]bool))
+	genericFieldsCache.Store(key, fields)
+	return fields
+}
+
+// collectGenericFields collects the fields of the struct type t. The fields of
+// the embedded structs are promoted unless shadowed by the outer fields.
+func collectGenericFields(t reflect.Type, tag string, index []int, seen map[string]bool) genericFields {
+	var fields genericFields
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, options, tagged := genericFieldTag(sf, tag)
+		if name == "-" && options == "" {
+			continue
+		}
+		if sf.Anonymous && !tagged && sf.Type.Kind() == reflect.Struct {
+			embedded = append(embedded, sf)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fields = append(fields, &genericField{
+			name:      name,
+			index:     append(append([]int(nil), index...), i),
+			omitEmpty: strings.Contains(","+options+",", ",omitempty,"),
+		})
+	}
+	for _, sf := range embedded {
+		fieldIndex := append(append([]int(nil), index...), sf.Index...)
+		fields = append(fields, collectGenericFields(sf.Type, tag, fieldIndex, seen)...)
+	}
+	return fields
+}
+
+// genericFieldTag returns the name and the options in the tag of the format,
+// or the "json" tag.
+func genericFieldTag(sf reflect.StructField, tag string) (name, options string, tagged bool) {
+	value, ok := sf.Tag.Lookup(tag)
+	if !ok {
+		value, ok = sf.Tag.Lookup("json")
+	}
+	if !ok {
+		return "", "", false
+	}
+	name, options, _ = strings.Cut(value, ",")
+	return name, options, name != ""
+}
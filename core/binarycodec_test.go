@@ -0,0 +1,114 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type TelemetryMeta struct {
+	Host   string `json:"host"`
+	Region string `msgpack:"zone" cbor:"zone" json:"region"`
+}
+
+type TelemetryPayload struct {
+	TelemetryMeta
+	ID        uint64                `json:"id"`
+	Offset    int32                 `json:"offset"`
+	Ratio     float64               `json:"ratio"`
+	Enabled   bool                  `json:"enabled"`
+	Tags      []string              `json:"tags"`
+	Raw       []byte                `json:"raw"`
+	Counters  map[string]int        `json:"counters"`
+	Reported  time.Time             `json:"reported"`
+	Parent    *TelemetryPayload     `json:"parent,omitempty"`
+	Note      patch.Field[string]   `json:"note"`
+	Threshold patch.Field[*float64] `json:"threshold"`
+	Skipped   string                `json:"-"`
+	Extra     any                   `json:"extra,omitempty"`
+}
+
+func sampleTelemetryPayload() *TelemetryPayload {
+	threshold := 0.75
+	return &TelemetryPayload{
+		TelemetryMeta: TelemetryMeta{Host: "web-1", Region: "eu"},
+		ID:            1 << 40,
+		Offset:        -300,
+		Ratio:         3.5,
+		Enabled:       true,
+		Tags:          []string{"a", "b"},
+		Raw:           []byte{0, 1, 2},
+		Counters:      map[string]int{"errors": 2, "requests": 1000},
+		Reported:      time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC),
+		Parent:        &TelemetryPayload{ID: 1, Tags: []string{}},
+		Note:          patch.Field[string]{Value: "ok", Valid: true},
+		Threshold:     patch.Field[*float64]{Value: &threshold, Valid: true},
+	}
+}
+
+func TestGeneric_RoundTrip(t *testing.T) {
+	payload := sampleTelemetryPayload()
+	payload.Skipped = "not encoded"
+	generic, err := toGeneric(reflect.ValueOf(payload), "msgpack")
+	assert.NoError(t, err)
+
+	m := generic.(genericMap)
+	var keys []any
+	for _, entry := range m {
+		keys = append(keys, entry.Key)
+	}
+	assert.Equal(t, []any{"id", "offset", "ratio", "enabled", "tags", "raw", "counters",
+		"reported", "parent", "note", "threshold", "host", "zone"}, keys)
+
+	var got TelemetryPayload
+	assert.NoError(t, fromGeneric(generic, reflect.ValueOf(&got).Elem(), "msgpack"))
+	payload.Skipped = ""
+	assert.Equal(t, payload, &got)
+}
+
+func TestGeneric_PatchField(t *testing.T) {
+	var got TelemetryPayload
+	generic := genericMap{{"note", nil}, {"id", int64(1)}}
+	assert.NoError(t, fromGeneric(generic, reflect.ValueOf(&got).Elem(), "cbor"))
+	assert.False(t, got.Note.Valid)
+	assert.False(t, got.Threshold.Valid)
+
+	encoded, err := toGeneric(reflect.ValueOf(got), "cbor")
+	assert.NoError(t, err)
+	assert.Contains(t, encoded.(genericMap), genericEntry{"note", nil})
+}
+
+func TestGeneric_Interface(t *testing.T) {
+	var got any
+	generic := genericMap{
+		{"list", []any{int64(1), uint64(1 << 63), "x"}},
+		{"nested", genericMap{{int64(1), "one"}, {[]byte("b"), true}}},
+	}
+	assert.NoError(t, fromGeneric(generic, reflect.ValueOf(&got).Elem(), "msgpack"))
+	assert.Equal(t, map[string]any{
+		"list":   []any{int64(1), uint64(1 << 63), "x"},
+		"nested": map[any]any{int64(1): "one", "b": true},
+	}, got)
+
+	err := fromGeneric(genericMap{{[]any{}, 1}}, reflect.ValueOf(&got).Elem(), "msgpack")
+	assert.ErrorContains(t, err, "unhashable map key of array")
+}
+
+func TestGeneric_Errors(t *testing.T) {
+	var payload TelemetryPayload
+	rv := reflect.ValueOf(&payload).Elem()
+	assert.ErrorContains(t, fromGeneric(genericMap{{"offset", int64(1 << 40)}}, rv, "msgpack"),
+		"field offset: value 1099511627776 overflows type int32")
+	assert.ErrorContains(t, fromGeneric(genericMap{{"id", int64(-1)}}, rv, "msgpack"),
+		"field id: cannot decode integer into type uint64")
+	assert.ErrorContains(t, fromGeneric(genericMap{{"tags", "a"}}, rv, "msgpack"),
+		"field tags: cannot decode string into type []string")
+	assert.ErrorContains(t, fromGeneric(genericMap{{"tags", []any{"a", true}}}, rv, "msgpack"),
+		"field tags: element at index 1: cannot decode boolean into type string")
+
+	_, err := toGeneric(reflect.ValueOf(map[string]any{"f": func() {}}), "msgpack")
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
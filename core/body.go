@@ -10,55 +10,124 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/ggicci/httpin/internal"
 )
 
 // ErrUnknownBodyFormat is returned when a serializer for the specified body format has not been specified.
+// It results in the status 500 Internal Server Error, see DefaultErrorStatusMapper.
 var ErrUnknownBodyFormat = errors.New("unknown body format")
 
-// DirectiveBody is the implementation of the "body" directive.
+// bodyFormatAuto is the argument of the "body" directive which accepts all the
+// registered body formats, e.g. `in:"body=auto"`.
+const bodyFormatAuto = "auto"
+
+// DirectiveBody is the implementation of the "body" directive. The argument is
+// the body format, which defaults to "json". When multiple formats are given,
+// e.g. `in:"body=json,xml"`, or "auto" for all the registered formats, the
+// format is selected by the Content-Type header of the request, see
+// RegisterBodyMediaTypes. An error of ErrUnsupportedMediaType is returned if
+// none of the formats matches. The first format is used when the Content-Type
+// header is absent, and when encoding.
+//
+// The "raw" and "text" formats bind the body to a []byte, string, io.Reader or
+// io.ReadCloser field as is, see bodyFormatRaw. The "ndjson" format, and the
+// "json" format of a stream type field, decode the elements lazily, see
+// bodyFormatNDJSON.
 type DirectiveBody struct{}
 
 func (db *DirectiveBody) Decode(rtm *DirectiveRuntime) error {
-	req := rtm.GetRequest()
-	bodyFormat, bodySerializer := db.getSerializer(rtm)
+	formats := db.getFormats(rtm)
+	bodyFormat := formats[0]
+	if len(formats) > 1 {
+		var err error
+		bodyFormat, err = negotiateBodyFormat(rtm.GetRequest().Header.Get("Content-Type"), formats)
+		if err != nil {
+			return err
+		}
+	}
+	if isRawBodyFormat(bodyFormat) {
+		return decodeRawBody(rtm)
+	}
+	if bodyFormat == bodyFormatNDJSON || isStreamType(rtm.Value.Elem().Type()) {
+		return decodeStreamBody(rtm, bodyFormat)
+	}
+	bodySerializer := getBodySerializer(bodyFormat)
 	if bodySerializer == nil {
 		return fmt.Errorf("%w: %q", ErrUnknownBodyFormat, bodyFormat)
 	}
-	if err := bodySerializer.Decode(req.Body, rtm.Value.Elem().Addr().Interface()); err != nil {
+	options := rtm.getBodyOptions()
+	bodySerializer = options.configure(bodySerializer)
+	body, err := rtm.getRequestBodyReader()
+	if err != nil {
 		return err
 	}
+	if err := bodySerializer.Decode(options.limit(body), rtm.Value.Elem().Addr().Interface()); err != nil {
+		return classifyRequestError(err)
+	}
 	return nil
 }
 
 func (db *DirectiveBody) Encode(rtm *DirectiveRuntime) error {
-	bodyFormat, bodySerializer := db.getSerializer(rtm)
+	bodyFormat := db.getFormats(rtm)[0]
+	if isRawBodyFormat(bodyFormat) {
+		return encodeRawBody(rtm, bodyFormat)
+	}
+	if bodyFormat == bodyFormatNDJSON || isStreamType(rtm.Value.Type()) {
+		return encodeStreamBody(rtm, bodyFormat)
+	}
+	bodySerializer := getBodySerializer(bodyFormat)
 	if bodySerializer == nil {
 		return fmt.Errorf("%w: %q", ErrUnknownBodyFormat, bodyFormat)
 	}
 	if bodyReader, err := bodySerializer.Encode(rtm.Value.Interface()); err != nil {
 		return err
 	} else {
-		rtm.GetRequestBuilder().SetBody(bodyFormat, io.NopCloser(bodyReader))
+		rb := rtm.GetRequestBuilder()
+		rb.SetBody(bodyFormat, io.NopCloser(bodyReader))
+		rb.contentType = rtm.getBodyOptions().contentType("")
 		rtm.MarkFieldSet(true)
 		return nil
 	}
 }
 
-func (*DirectiveBody) getSerializer(rtm *DirectiveRuntime) (bodyFormat string, serializer BodySerializer) {
-	bodyFormat = "json"
-	if len(rtm.Directive.Argv) > 0 {
-		bodyFormat = strings.ToLower(rtm.Directive.Argv[0])
+// getFormats returns the body formats accepted by the directive, which is never
+// empty.
+func (*DirectiveBody) getFormats(rtm *DirectiveRuntime) []string {
+	argv := rtm.Directive.Argv
+	if len(argv) == 0 {
+		return []string{"json"}
+	}
+	if len(argv) == 1 && strings.EqualFold(argv[0], bodyFormatAuto) {
+		return registeredBodyFormats()
 	}
-	serializer = getBodySerializer(bodyFormat)
-	return
+	formats := make([]string, len(argv))
+	for i, format := range argv {
+		formats[i] = strings.ToLower(format)
+	}
+	return formats
 }
 
 var bodyFormats = map[string]BodySerializer{
-	"json": &JSONBody{},
-	"xml":  &XMLBody{},
+	"json":    &JSONBody{},
+	"xml":     &XMLBody{},
+	"yaml":    &YAMLBody{},
+	"msgpack": &MessagePackBody{},
+	"cbor":    &CBORBody{},
+}
+
+// bodyMediaTypes are the media types of the body formats. The first one is used
+// as the Content-Type when encoding. See RegisterBodyMediaTypes.
+var bodyMediaTypes = map[string][]string{
+	"json":    {"application/json"},
+	"xml":     {"application/xml", "text/xml"},
+	"yaml":    {"application/yaml", "application/x-yaml", "text/yaml"},
+	"msgpack": {"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+	"cbor":    {"application/cbor"},
 }
 
 // BodySerializer is the interface for encoding and decoding the request body.
@@ -77,6 +146,12 @@ type BodySerializer interface {
 // The BodyEncoderDecoder is used by the body directive to decode and encode the data in
 // the given format (body format).
 //
+// RegisterBodyFormat doesn't register any media types. Unless set by
+// RegisterBodyMediaTypes, the body format only matches the Content-Type
+// "application/<format>" and the "+<format>" suffix, e.g. "application/toml",
+// and the same media type is sent when encoding. Replacing a body format keeps
+// its media types.
+//
 // It is also useful when you want to override the default registered
 // BodyEncoderDecoder. For example, the default JSON decoder is borrowed from
 // encoding/json. You can replace it with your own implementation, e.g.
@@ -84,22 +159,167 @@ type BodySerializer interface {
 //
 //	func init() {
 //	    RegisterBodyFormat("json", &myJSONBody{}, true) // force register, replace the old one
-//	    RegisterBodyFormat("yaml", &myYAMLBody{}) // register a new body format "yaml"
+//	    RegisterBodyFormat("toml", &myTOMLBody{}) // register a new body format "toml"
 //	}
+//
+// The names "auto", "raw", "text" and "ndjson" are reserved.
 func RegisterBodyFormat(format string, body BodySerializer, force ...bool) {
 	internal.PanicOnError(
 		registerBodyFormat(format, body, force...),
 	)
 }
 
+// RegisterBodyMediaTypes sets the media types of a registered body format,
+// which are used to select the body format by the Content-Type header of the
+// request, and the first one is set as the Content-Type header when encoding.
+// Panics on unregistered body format or invalid media types. For example:
+//
+//	func init() {
+//	    RegisterBodyFormat("yaml", &myYAMLBody{})
+//	    RegisterBodyMediaTypes("yaml", "application/yaml", "application/x-yaml", "text/yaml")
+//	}
+//
+// The media types of a body format default to "application/<format>", e.g.
+// "application/yaml". Besides, the media types with the structured syntax
+// suffix of a body format, e.g. "application/problem+json", are selected for
+// the body format as well.
+func RegisterBodyMediaTypes(format string, mediaTypes ...string) {
+	internal.PanicOnError(
+		registerBodyMediaTypes(format, mediaTypes...),
+	)
+}
+
 func getBodySerializer(bodyFormat string) BodySerializer {
 	return bodyFormats[bodyFormat]
 }
 
-type JSONBody struct{}
+func getBodyMediaTypes(bodyFormat string) []string {
+	if mediaTypes := bodyMediaTypes[bodyFormat]; len(mediaTypes) > 0 {
+		return mediaTypes
+	}
+	return []string{"application/" + bodyFormat}
+}
+
+// registeredBodyFormats returns all the registered body formats. The built-in
+// formats, i.e. json and xml, come first, followed by the others in
+// alphabetical order.
+func registeredBodyFormats() []string {
+	formats := make([]string, 0, len(bodyFormats))
+	for format := range bodyFormats {
+		if format != "json" && format != "xml" {
+			formats = append(formats, format)
+		}
+	}
+	sort.Strings(formats)
+	return append([]string{"json", "xml"}, formats...)
+}
+
+// negotiateBodyFormat selects the body format among formats by the
+// Content-Type header. The first format is selected if contentType is empty.
+func negotiateBodyFormat(contentType string, formats []string) (string, error) {
+	if contentType == "" {
+		return formats[0], nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrUnsupportedMediaType, contentType)
+	}
+	for _, format := range formats {
+		for _, candidate := range getBodyMediaTypes(format) {
+			if mediaType == candidate {
+				return format, nil
+			}
+		}
+	}
+	for _, format := range formats {
+		if strings.HasSuffix(mediaType, "+"+format) {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnsupportedMediaType, mediaType)
+}
+
+// JSONBody is the BodySerializer of the "json" body format, which is
+// implemented by encoding/json. The options are set by the "strict" and
+// "usenumber" directives per field.
+type JSONBody struct {
+	// DisallowUnknownFields makes the decoding fail when the object has a key
+	// which does not match any field of the destination struct.
+	DisallowUnknownFields bool
+
+	// DisallowTrailingData makes the decoding fail when there's data other
+	// than whitespace after the top-level value.
+	DisallowTrailingData bool
+
+	// UseNumber decodes a number into an interface{} as a json.Number instead
+	// of as a float64.
+	UseNumber bool
+}
 
 func (de *JSONBody) Decode(src io.Reader, dst any) error {
-	return json.NewDecoder(src).Decode(dst)
+	decoder := de.newDecoder(src)
+	if err := de.decodeValue(decoder, dst); err != nil {
+		return err
+	}
+	if de.DisallowTrailingData {
+		return checkTrailingJSONData(decoder)
+	}
+	return nil
+}
+
+func (de *JSONBody) newDecoder(src io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(src)
+	if de.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if de.UseNumber {
+		decoder.UseNumber()
+	}
+	return decoder
+}
+
+// decodeValue decodes the next value, an unknown field results in an error of
+// CodeUnknownField.
+func (de *JSONBody) decodeValue(decoder *json.Decoder, dst any) error {
+	if !de.DisallowUnknownFields {
+		return decoder.Decode(dst)
+	}
+	var raw json.RawMessage
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	err := de.newDecoder(bytes.NewReader(raw)).Decode(dst)
+	var invalidUnmarshalError *json.InvalidUnmarshalError
+	if err == nil || errors.As(err, &invalidUnmarshalError) {
+		return err
+	}
+
+	// encoding/json has no error type of the unknown fields. Tell them apart
+	// by decoding the value again, allowing the unknown fields.
+	lenient := &JSONBody{UseNumber: de.UseNumber}
+	probe := reflect.New(reflect.TypeOf(dst).Elem()).Interface()
+	if lenient.newDecoder(bytes.NewReader(raw)).Decode(probe) != nil {
+		return err
+	}
+	// The name of the field is only available in the message, which is still
+	// parsed for the "name" param, and omitted if the message doesn't match.
+	var params map[string]any
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		params = map[string]any{"name": strings.Trim(name, `"`)}
+	}
+	return NewCodedError(CodeUnknownField, params, err)
+}
+
+// checkTrailingJSONData returns an error of ErrMalformedRequest if there's
+// data other than whitespace after the last decoded value.
+func checkTrailingJSONData(decoder *json.Decoder) error {
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errors.New("invalid data after top-level value")
+		}
+		return fmt.Errorf("%w: %w", ErrMalformedRequest, err)
+	}
+	return nil
 }
 
 func (en *JSONBody) Encode(src any) (io.Reader, error) {
@@ -133,9 +353,32 @@ func registerBodyFormat(format string, body BodySerializer, force ...bool) error
 	if format == "" {
 		return errors.New("body format cannot be empty")
 	}
+	if isRawBodyFormat(format) || format == bodyFormatNDJSON || format == bodyFormatAuto {
+		return fmt.Errorf("reserved body format: %q", format)
+	}
 	if body == nil {
 		return errors.New("body serializer cannot be nil")
 	}
 	bodyFormats[format] = body
 	return nil
 }
+
+func registerBodyMediaTypes(format string, mediaTypes ...string) error {
+	format = strings.ToLower(format)
+	if getBodySerializer(format) == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownBodyFormat, format)
+	}
+	if len(mediaTypes) == 0 {
+		return errors.New("missing media types")
+	}
+	normalized := make([]string, len(mediaTypes))
+	for i, mediaType := range mediaTypes {
+		parsed, params, err := mime.ParseMediaType(mediaType)
+		if err != nil || len(params) > 0 {
+			return fmt.Errorf("invalid media type: %q", mediaType)
+		}
+		normalized[i] = parsed
+	}
+	bodyMediaTypes[format] = normalized
+	return nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -128,7 +129,7 @@ func TestBodyDirective_Decode_XML(t *testing.T) {
 
 func TestBodyDirective_Decode_ErrUnknownBodyFormat(t *testing.T) {
 	type UnknownBodyFormatPayload struct {
-		Body *BodyPayload `in:"body=yaml"`
+		Body *BodyPayload `in:"body=toml"`
 	}
 
 	co, err := New(UnknownBodyFormatPayload{})
@@ -136,7 +137,11 @@ func TestBodyDirective_Decode_ErrUnknownBodyFormat(t *testing.T) {
 	req, _ := http.NewRequest("GET", "https://example.com", nil)
 	req.Body = makeBodyReader(sampleBodyPayloadInJSONText)
 	_, err = co.Decode(req)
-	assert.ErrorContains(t, err, "unknown body format: \"yaml\"")
+	assert.ErrorContains(t, err, "unknown body format: \"toml\"")
+	assert.Equal(t, http.StatusInternalServerError, DefaultErrorStatusMapper(err))
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, CodeUnknownBodyFormat, invalidField.Code)
 }
 
 func TestBodyDirective_Decode_ErrConflictWithFormDirective(t *testing.T) {
@@ -151,40 +156,81 @@ func TestBodyDirective_Decode_ErrConflictWithFormDirective(t *testing.T) {
 	assert.Nil(t, req)
 }
 
-type yamlBody struct{}
+func TestBodyDirective_Decode_WithBodyReplay(t *testing.T) {
+	type Payload struct {
+		Body    *BodyPayload   `in:"body=json"`
+		RawBody map[string]any `in:"body=json"`
+	}
+
+	co, err := New(Payload{}, WithBodyReplay(1024))
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("POST", "https://example.com", strings.NewReader(sampleBodyPayloadInJSONText))
+	r.Header.Set("Content-Type", "application/json")
+	gotValue, err := co.Decode(r)
+	assert.NoError(t, err)
+	got := gotValue.(*Payload)
+	assert.Equal(t, sampleBodyPayloadInJSONObject.Body, got.Body)
+	assert.Equal(t, "Elia", got.RawBody["name"])
+
+	// The body is restored after decoding.
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleBodyPayloadInJSONText, string(body))
+	assert.NotNil(t, r.GetBody)
+	rc, err := r.GetBody()
+	assert.NoError(t, err)
+	body, _ = io.ReadAll(rc)
+	assert.Equal(t, sampleBodyPayloadInJSONText, string(body))
+}
+
+func TestBodyDirective_Decode_WithBodyReplay_BodyTooLarge(t *testing.T) {
+	co, err := New(BodyPayloadInJSON{}, WithBodyReplay(16))
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("POST", "https://example.com", strings.NewReader(sampleBodyPayloadInJSONText))
+	r.Header.Set("Content-Type", "application/json")
+	gotValue, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleBodyPayloadInJSONObject, gotValue)
+
+	// Not replayable.
+	body, _ := io.ReadAll(r.Body)
+	assert.Empty(t, body)
+}
+
+type tomlBody struct{}
 
-var errYamlNotImplemented = errors.New("yaml not implemented")
+var errTomlNotImplemented = errors.New("toml not implemented")
 
-func (de *yamlBody) Decode(src io.Reader, dst any) error {
-	return errYamlNotImplemented // for test only
+func (de *tomlBody) Decode(src io.Reader, dst any) error {
+	return errTomlNotImplemented // for test only
 }
 
-func (en *yamlBody) Encode(src any) (io.Reader, error) {
-	return nil, errYamlNotImplemented // for test only
+func (en *tomlBody) Encode(src any) (io.Reader, error) {
+	return nil, errTomlNotImplemented // for test only
 }
 
-type YamlInput struct {
-	Body map[string]any `in:"body=yaml"`
+type TomlInput struct {
+	Body map[string]any `in:"body=toml"`
 }
 
 func TestRegisterBodyFormat(t *testing.T) {
 	assert.NotPanics(t, func() {
-		RegisterBodyFormat("yaml", &yamlBody{})
+		RegisterBodyFormat("toml", &tomlBody{})
 	})
 	assert.Panics(t, func() {
-		RegisterBodyFormat("yaml", &yamlBody{})
+		RegisterBodyFormat("toml", &tomlBody{})
 	})
 
-	co, err := New(YamlInput{})
+	co, err := New(TomlInput{})
 	assert.NoError(t, err)
 
 	r, _ := http.NewRequest("GET", "https://example.com", nil)
 	r.Body = makeBodyReader(`version: "3"`)
 
 	gotValue, err := co.Decode(r)
-	assert.ErrorIs(t, err, errYamlNotImplemented)
+	assert.ErrorIs(t, err, errTomlNotImplemented)
 	assert.Nil(t, gotValue)
-	unregisterBodyFormat("yaml")
+	unregisterBodyFormat("toml")
 }
 
 func TestRegisterBodyFormat_ErrNilBodySerializer(t *testing.T) {
@@ -195,17 +241,17 @@ func TestRegisterBodyFormat_ErrNilBodySerializer(t *testing.T) {
 
 func TestRegisterBodyFormat_ForceRegister(t *testing.T) {
 	assert.NotPanics(t, func() {
-		RegisterBodyFormat("yaml", &yamlBody{}, true)
+		RegisterBodyFormat("toml", &tomlBody{}, true)
 	})
 	assert.NotPanics(t, func() {
-		RegisterBodyFormat("yaml", &yamlBody{}, true)
+		RegisterBodyFormat("toml", &tomlBody{}, true)
 	})
-	unregisterBodyFormat("yaml")
+	unregisterBodyFormat("toml")
 }
 
 func TestRegisterBodyFormat_ForceRegisterWithEmptyBodyFormat(t *testing.T) {
 	assert.PanicsWithError(t, "httpin: body format cannot be empty", func() {
-		RegisterBodyFormat("", &yamlBody{}, true)
+		RegisterBodyFormat("", &tomlBody{}, true)
 	})
 }
 
@@ -253,7 +299,7 @@ func TestBodyDirective_NewRequest_XML(t *testing.T) {
 
 func TestBodyDirective_NewRequest_ErrUnknownBodyFormat(t *testing.T) {
 	type UnknownBodyFormatPayload struct {
-		Body *BodyPayload `in:"body=yaml"`
+		Body *BodyPayload `in:"body=toml"`
 	}
 	query := &UnknownBodyFormatPayload{
 		Body: nil,
@@ -261,12 +307,108 @@ func TestBodyDirective_NewRequest_ErrUnknownBodyFormat(t *testing.T) {
 	co, err := New(UnknownBodyFormatPayload{})
 	assert.NoError(t, err)
 	req, err := co.NewRequest("PUT", "/apples/10", query)
-	assert.ErrorContains(t, err, "unknown body format: \"yaml\"")
+	assert.ErrorContains(t, err, "unknown body format: \"toml\"")
 	assert.Nil(t, req)
 }
 
+func TestBodyDirective_Auto(t *testing.T) {
+	type AutoBodyInput struct {
+		Body *BodyPayload `in:"body=auto"`
+	}
+	co, err := New(AutoBodyInput{})
+	assert.NoError(t, err)
+
+	decode := func(contentType, body string) (*BodyPayload, error) {
+		r, _ := http.NewRequest("POST", "/data", strings.NewReader(body))
+		if contentType != "" {
+			r.Header.Set("Content-Type", contentType)
+		}
+		got, err := co.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		return got.(*AutoBodyInput).Body, nil
+	}
+
+	for _, c := range []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json", sampleBodyPayloadInJSONText},
+		{"application/json; charset=utf-8", sampleBodyPayloadInJSONText},
+		{"application/merge-patch+json", sampleBodyPayloadInJSONText},
+		{"", sampleBodyPayloadInJSONText}, // defaults to the first format
+		{"application/xml", sampleBodyPayloadInXMLText},
+		{"text/xml", sampleBodyPayloadInXMLText},
+	} {
+		got, err := decode(c.contentType, c.body)
+		assert.NoError(t, err, c.contentType)
+		assert.Equal(t, sampleBodyPayloadInJSONObject.Body, got, c.contentType)
+	}
+
+	_, err = decode("text/plain", "hello")
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+	assert.Equal(t, http.StatusUnsupportedMediaType, DefaultErrorStatusMapper(err))
+
+	// The first format is used when encoding.
+	req, err := co.NewRequest("POST", "/data", &AutoBodyInput{Body: sampleBodyPayloadInJSONObject.Body})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+}
+
+func TestBodyDirective_FormatList(t *testing.T) {
+	RegisterBodyFormat("toml", &tomlBody{})
+	RegisterBodyMediaTypes("toml", "application/toml", "Application/X-TOML")
+	defer unregisterBodyFormat("toml")
+
+	type ListBodyInput struct {
+		Body map[string]any `in:"body=toml,json"`
+	}
+	co, err := New(ListBodyInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/data", strings.NewReader(`version: "3"`))
+	r.Header.Set("Content-Type", "application/x-toml")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, errTomlNotImplemented)
+
+	r, _ = http.NewRequest("POST", "/data", strings.NewReader(`{"version": "3"}`))
+	r.Header.Set("Content-Type", "application/json")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"version": "3"}, got.(*ListBodyInput).Body)
+
+	r, _ = http.NewRequest("POST", "/data", strings.NewReader(`<version>3</version>`))
+	r.Header.Set("Content-Type", "application/xml")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+
+	rb := NewRequestBuilder(context.Background())
+	rb.SetBody("toml", makeBodyReader(`version: "3"`))
+	assert.Equal(t, "application/toml", rb.bodyContentType())
+}
+
+func TestRegisterBodyMediaTypes(t *testing.T) {
+	assert.PanicsWithError(t, `httpin: unknown body format: "ini"`, func() {
+		RegisterBodyMediaTypes("ini", "application/ini")
+	})
+	assert.PanicsWithError(t, "httpin: missing media types", func() {
+		RegisterBodyMediaTypes("json")
+	})
+	assert.PanicsWithError(t, `httpin: invalid media type: "application/json; charset=utf-8"`, func() {
+		RegisterBodyMediaTypes("json", "application/json; charset=utf-8")
+	})
+
+	// Defaults to "application/<format>".
+	RegisterBodyFormat("ini", &tomlBody{})
+	defer unregisterBodyFormat("ini")
+	assert.Equal(t, []string{"application/ini"}, getBodyMediaTypes("ini"))
+	assert.Equal(t, []string{"json", "xml", "cbor", "ini", "msgpack", "yaml"}, registeredBodyFormats())
+}
+
 func unregisterBodyFormat(format string) {
 	delete(bodyFormats, format)
+	delete(bodyMediaTypes, format)
 }
 
 func makeBodyReader(text string) io.ReadCloser {
@@ -0,0 +1,157 @@
+// directive: "strict", "usenumber", "maxbytes", "type"
+// https://ggicci.github.io/httpin/directives/body
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/ggicci/owl"
+)
+
+// bodyOptions are the options of the "body" directive, specified by the
+// following directives:
+//
+//   - strict: disallows the unknown fields and the trailing data after the
+//     top-level JSON value;
+//   - usenumber: decodes the JSON numbers into json.Number rather than
+//     float64, when the destination is an interface{};
+//   - maxbytes: limits the size of the body, an error of ErrRequestTooLarge is
+//     returned when exceeded. See also WithMaxBodyBytes;
+//   - type: sets the Content-Type of the body when encoding, which overrides
+//     the media type of the body format, e.g. `in:"body=raw;type=image/png"`.
+//     The media type can't have parameters, since ";" separates directives.
+//     Unlike the others, it's a body option only along with the "body"
+//     directive, and the name is not reserved, i.e. a user defined "type"
+//     directive can still be used on the other fields.
+//
+// For example:
+//
+//	type CreateUserInput struct {
+//		Payload *User `in:"body=json;strict;usenumber;maxbytes=65536"`
+//	}
+//
+// Like the "coder" directive, these directives will be removed from the
+// resolver during the resolver building phase, and the bodyOptions will be
+// put into Resolver.Context. The strict and usenumber options only apply to the
+// built-in JSON body format, i.e. JSONBody.
+type bodyOptions struct {
+	Strict      bool
+	UseNumber   bool
+	MaxBytes    int64  // 0 means unlimited
+	ContentType string // empty means the media type of the body format
+}
+
+var bodyOptionDirectives = []string{"strict", "usenumber", "maxbytes"}
+
+// bodyTypeDirective is the body option directive "type", see bodyOptions.
+const bodyTypeDirective = "type"
+
+func (rtm *DirectiveRuntime) getBodyOptions() *bodyOptions {
+	if options, ok := rtm.Resolver.Context.Value(ctxBodyOptions).(*bodyOptions); ok {
+		return options
+	}
+	return nil
+}
+
+// limit limits the size of the body reader, see MaxBytes.
+func (o *bodyOptions) limit(body io.Reader) io.Reader {
+	if o == nil || o.MaxBytes <= 0 {
+		return body
+	}
+	return http.MaxBytesReader(nil, toReadCloser(body), o.MaxBytes)
+}
+
+// contentType returns the Content-Type of the body when encoding, see
+// ContentType. defaultType is returned when not specified.
+func (o *bodyOptions) contentType(defaultType string) string {
+	if o == nil || o.ContentType == "" {
+		return defaultType
+	}
+	return o.ContentType
+}
+
+// configure returns the serializer configured by the options. Only JSONBody is
+// configurable, the others are returned as is.
+func (o *bodyOptions) configure(serializer BodySerializer) BodySerializer {
+	jsonBody, ok := serializer.(*JSONBody)
+	if o == nil || !ok || (!o.Strict && !o.UseNumber) {
+		return serializer
+	}
+	configured := *jsonBody
+	configured.DisallowUnknownFields = configured.DisallowUnknownFields || o.Strict
+	configured.DisallowTrailingData = configured.DisallowTrailingData || o.Strict
+	configured.UseNumber = configured.UseNumber || o.UseNumber
+	return &configured
+}
+
+// reserveBodyOptionDirectives removes the body option directives from the
+// resolver, and puts the bodyOptions into Resolver.Context.
+func reserveBodyOptionDirectives(r *owl.Resolver) error {
+	var options *bodyOptions
+	names := bodyOptionDirectives
+	if r.GetDirective("body") != nil {
+		names = append(names[:len(names):len(names)], bodyTypeDirective)
+	}
+	for _, name := range names {
+		d := r.RemoveDirective(name)
+		if d == nil {
+			continue
+		}
+		if options == nil {
+			options = &bodyOptions{}
+		}
+		if err := options.set(d); err != nil {
+			return fmt.Errorf("directive %s: %w", name, err)
+		}
+	}
+	if options == nil {
+		return nil
+	}
+	if r.GetDirective("body") == nil {
+		return errors.New("directives strict, usenumber and maxbytes require the body directive")
+	}
+	r.Context = context.WithValue(r.Context, ctxBodyOptions, options)
+	return nil
+}
+
+func (o *bodyOptions) set(d *owl.Directive) error {
+	if d.Name == "maxbytes" {
+		if len(d.Argv) != 1 {
+			return errors.New("expect exactly one argument")
+		}
+		maxBytes, err := strconv.ParseInt(d.Argv[0], 10, 64)
+		if err != nil || maxBytes <= 0 {
+			return fmt.Errorf("invalid max bytes %q", d.Argv[0])
+		}
+		o.MaxBytes = maxBytes
+		return nil
+	}
+	if d.Name == bodyTypeDirective {
+		if len(d.Argv) != 1 {
+			return errors.New("expect exactly one argument")
+		}
+		mediaType, _, err := mime.ParseMediaType(d.Argv[0])
+		if err != nil {
+			return fmt.Errorf("invalid media type %q", d.Argv[0])
+		}
+		o.ContentType = mediaType
+		return nil
+	}
+
+	if len(d.Argv) > 0 {
+		return fmt.Errorf("unexpected arguments %v", d.Argv)
+	}
+	if d.Name == "strict" {
+		o.Strict = true
+	} else {
+		o.UseNumber = true
+	}
+	return nil
+}
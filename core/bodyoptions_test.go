@@ -0,0 +1,133 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type StrictBodyInput struct {
+	Payload *BodyPayload `in:"body=json;strict"`
+}
+
+type NumberBodyInput struct {
+	Payload map[string]any `in:"body=json;usenumber;maxbytes=32"`
+}
+
+func newJSONRequest(body string) *http.Request {
+	r, _ := http.NewRequest("POST", "/data", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func TestBodyOptions_Strict(t *testing.T) {
+	co, err := New(StrictBodyInput{})
+	assert.NoError(t, err)
+
+	got, err := co.Decode(newJSONRequest(sampleBodyPayloadInJSONText + "\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, sampleBodyPayloadInJSONObject.Body, got.(*StrictBodyInput).Payload)
+
+	// Unknown fields.
+	_, err = co.Decode(newJSONRequest(`{"name": "Elia", "nickname": "E"}`))
+	var fe *InvalidFieldError
+	assert.ErrorAs(t, err, &fe)
+	assert.Equal(t, CodeUnknownField, fe.Code)
+	assert.Equal(t, map[string]any{"name": "nickname"}, fe.Params)
+	assert.Equal(t, http.StatusUnprocessableEntity, DefaultErrorStatusMapper(err))
+
+	// Trailing data.
+	for _, body := range []string{`{"name": "Elia"} {}`, `{"name": "Elia"}}`, `{"name": "Elia"} garbage`} {
+		_, err = co.Decode(newJSONRequest(body))
+		assert.ErrorIs(t, err, ErrMalformedRequest, body)
+		assert.Equal(t, 1, strings.Count(err.Error(), ErrMalformedRequest.Error()), err)
+		assert.Equal(t, http.StatusBadRequest, DefaultErrorStatusMapper(err), body)
+	}
+
+	// Without strict, both are allowed.
+	co, err = New(BodyPayloadInJSON{})
+	assert.NoError(t, err)
+	_, err = co.Decode(newJSONRequest(`{"name": "Elia", "nickname": "E"} {}`))
+	assert.NoError(t, err)
+}
+
+func TestJSONBody_Decode_UnknownField(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+	}
+	type User struct {
+		Name    string   `json:"name"`
+		Age     int      `json:"age"`
+		Address *Address `json:"address"`
+	}
+	strict := &JSONBody{DisallowUnknownFields: true}
+
+	for _, c := range []struct {
+		body string
+		name string
+	}{
+		{`{"name": "Elia", "nickname": "E"}`, "nickname"},
+		{`{"name": "Elia", "address": {"city": "Oslo", "zip": "0150"}}`, "zip"},
+	} {
+		var user User
+		err := strict.Decode(strings.NewReader(c.body), &user)
+		var coded *CodedError
+		if assert.ErrorAs(t, err, &coded, c.body) {
+			assert.Equal(t, CodeUnknownField, coded.Code)
+			assert.Equal(t, map[string]any{"name": c.name}, coded.Params)
+		}
+	}
+
+	// The other errors are not of CodeUnknownField.
+	for _, body := range []string{`{"age": "ten"}`, `{"name": `, `[]`} {
+		var user User
+		err := strict.Decode(strings.NewReader(body), &user)
+		assert.Error(t, err, body)
+		var coded *CodedError
+		assert.False(t, errors.As(err, &coded), body)
+	}
+
+	// Decoding into a non-pointer fails as usual.
+	var user User
+	assert.Error(t, strict.Decode(strings.NewReader(`{}`), user))
+}
+
+func TestBodyOptions_UseNumberAndMaxBytes(t *testing.T) {
+	co, err := New(NumberBodyInput{})
+	assert.NoError(t, err)
+
+	got, err := co.Decode(newJSONRequest(`{"id": 9007199254740993}`))
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("9007199254740993"), got.(*NumberBodyInput).Payload["id"])
+
+	_, err = co.Decode(newJSONRequest(`{"id": 9007199254740993, "name": "Elia"}`))
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, DefaultErrorStatusMapper(err))
+}
+
+func TestBodyOptions_Invalid(t *testing.T) {
+	for _, c := range []struct {
+		input any
+		err   string
+	}{
+		{struct {
+			Name string `in:"query=name;strict"`
+		}{}, "require the body directive"},
+		{struct {
+			Payload map[string]any `in:"body=json;strict=yes"`
+		}{}, "directive strict: unexpected arguments [yes]"},
+		{struct {
+			Payload map[string]any `in:"body=json;maxbytes"`
+		}{}, "directive maxbytes: expect exactly one argument"},
+		{struct {
+			Payload map[string]any `in:"body=json;maxbytes=-1"`
+		}{}, `directive maxbytes: invalid max bytes "-1"`},
+	} {
+		_, err := New(c.input)
+		assert.ErrorContains(t, err, c.err)
+	}
+}
@@ -0,0 +1,403 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// CBORBody is the BodySerializer of the "cbor" body format, see RFC 8949. The
+// struct fields are named by the "cbor" tag, or the "json" tag. time.Time is
+// encoded as a standard date/time string (tag 0), and both the tag 0 and the
+// epoch-based date/time (tag 1) are accepted when decoding. The other tags are
+// ignored, i.e. the tagged values are decoded as is.
+type CBORBody struct{}
+
+func (de *CBORBody) Decode(src io.Reader, dst any) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return io.EOF
+	}
+	d := &cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return err
+	}
+	if value == cborBreak {
+		return fmt.Errorf("%w: cbor: unexpected break", ErrMalformedRequest)
+	}
+	return fromGeneric(value, reflect.ValueOf(dst).Elem(), "cbor")
+}
+
+func (en *CBORBody) Encode(src any) (io.Reader, error) {
+	value, err := toGeneric(reflect.ValueOf(src), "cbor")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encodeCBOR(&buf, value); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// The major types of CBOR.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+const (
+	cborTagDateTime = 0
+	cborTagEpoch    = 1
+)
+
+// cborBreak is the "break" stop code of the indefinite-length items.
+type cborBreakCode struct{}
+
+var cborBreak = cborBreakCode{}
+
+func encodeCBOR(buf *bytes.Buffer, v any) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case bool:
+		if value {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case int64:
+		if value >= 0 {
+			encodeCBORHead(buf, cborUint, uint64(value))
+		} else {
+			encodeCBORHead(buf, cborNegInt, uint64(-1-value))
+		}
+	case uint64:
+		encodeCBORHead(buf, cborUint, value)
+	case float32:
+		buf.WriteByte(cborSimple<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(value)))
+	case float64:
+		buf.WriteByte(cborSimple<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(value)))
+	case string:
+		encodeCBORHead(buf, cborText, uint64(len(value)))
+		buf.WriteString(value)
+	case []byte:
+		encodeCBORHead(buf, cborBytes, uint64(len(value)))
+		buf.Write(value)
+	case time.Time:
+		encodeCBORHead(buf, cborTag, cborTagDateTime)
+		return encodeCBOR(buf, value.Format(time.RFC3339Nano))
+	case []any:
+		encodeCBORHead(buf, cborArray, uint64(len(value)))
+		for _, elem := range value {
+			if err := encodeCBOR(buf, elem); err != nil {
+				return err
+			}
+		}
+	case genericMap:
+		encodeCBORHead(buf, cborMap, uint64(len(value)))
+		for _, entry := range value {
+			if err := encodeCBOR(buf, entry.Key); err != nil {
+				return err
+			}
+			if err := encodeCBOR(buf, entry.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+	}
+	return nil
+}
+
+// encodeCBORHead writes the initial byte and the argument in the shortest form.
+func encodeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	switch {
+	case n < 24:
+		buf.WriteByte(major<<5 | byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{major<<5 | 24, byte(n)})
+	case n <= math.MaxUint16:
+		buf.Write(binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.Write(binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n)))
+	default:
+		buf.Write(binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n))
+	}
+}
+
+type cborDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *cborDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// readHead reads the major type and the argument. indefinite is true when the
+// additional information is 31.
+func (d *cborDecoder) readHead() (major byte, info byte, arg uint64, indefinite bool, err error) {
+	b, err := d.read(1)
+	if err != nil {
+		return
+	}
+	major, info = b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		var v []byte
+		if v, err = d.read(size); err != nil {
+			return
+		}
+		for _, c := range v {
+			arg = arg<<8 | uint64(c)
+		}
+	case info == 31:
+		indefinite = true
+	default:
+		err = fmt.Errorf("%w: cbor: invalid additional information %d", ErrMalformedRequest, info)
+	}
+	return
+}
+
+// checkLength checks the length against the remaining data, each item takes
+// minSize bytes at least.
+func (d *cborDecoder) checkLength(n uint64, minSize int) (int, error) {
+	if n > uint64(len(d.data)-d.pos)/uint64(minSize) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxGenericDepth {
+		return nil, fmt.Errorf("%w: cbor: %w", ErrMalformedRequest, errGenericTooDeep)
+	}
+	major, info, arg, indefinite, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major == cborUint || major == cborNegInt || major == cborTag) {
+		return nil, fmt.Errorf("%w: cbor: invalid indefinite length of major type %d", ErrMalformedRequest, major)
+	}
+
+	switch major {
+	case cborUint:
+		if arg <= math.MaxInt64 {
+			return int64(arg), nil
+		}
+		return arg, nil
+	case cborNegInt:
+		if arg > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer -1-%d overflows int64", arg)
+		}
+		return -1 - int64(arg), nil
+	case cborBytes, cborText:
+		b, err := d.decodeString(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+		return b, nil
+	case cborArray:
+		return d.decodeArray(arg, indefinite, depth)
+	case cborMap:
+		return d.decodeMap(arg, indefinite, depth)
+	case cborTag:
+		return d.decodeTagged(arg, depth)
+	}
+
+	// Major type 7: simple values and floats.
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null, undefined
+		return nil, nil
+	case 25:
+		return halfToFloat32(uint16(arg)), nil
+	case 26:
+		return math.Float32frombits(uint32(arg)), nil
+	case 27:
+		return math.Float64frombits(arg), nil
+	case 31:
+		return cborBreak, nil
+	}
+	return nil, fmt.Errorf("%w: cbor: unsupported simple value %d", ErrMalformedRequest, arg)
+}
+
+func (d *cborDecoder) decodeString(major byte, n uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		size, err := d.checkLength(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(size)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	}
+
+	// The chunks of an indefinite-length string are definite-length strings of
+	// the same major type.
+	result := []byte{}
+	for {
+		chunkMajor, info, arg, chunkIndefinite, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor == cborSimple && info == 31 {
+			return result, nil
+		}
+		if chunkMajor != major || chunkIndefinite {
+			return nil, fmt.Errorf("%w: cbor: invalid chunk of indefinite-length string", ErrMalformedRequest)
+		}
+		chunk, err := d.decodeString(major, arg, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk...)
+	}
+}
+
+func (d *cborDecoder) decodeArray(n uint64, indefinite bool, depth int) (any, error) {
+	var elems []any
+	if !indefinite {
+		size, err := d.checkLength(n, 1)
+		if err != nil {
+			return nil, err
+		}
+		elems = make([]any, 0, size)
+	}
+	for i := uint64(0); indefinite || i < n; i++ {
+		elem, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if elem == cborBreak {
+			if !indefinite {
+				return nil, fmt.Errorf("%w: cbor: unexpected break", ErrMalformedRequest)
+			}
+			break
+		}
+		elems = append(elems, elem)
+	}
+	if elems == nil {
+		elems = []any{}
+	}
+	return elems, nil
+}
+
+func (d *cborDecoder) decodeMap(n uint64, indefinite bool, depth int) (any, error) {
+	var m genericMap
+	if !indefinite {
+		size, err := d.checkLength(n, 2)
+		if err != nil {
+			return nil, err
+		}
+		m = make(genericMap, 0, size)
+	}
+	for i := uint64(0); indefinite || i < n; i++ {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if key == cborBreak {
+			if !indefinite {
+				return nil, fmt.Errorf("%w: cbor: unexpected break", ErrMalformedRequest)
+			}
+			break
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if value == cborBreak {
+			return nil, fmt.Errorf("%w: cbor: unexpected break", ErrMalformedRequest)
+		}
+		m = append(m, genericEntry{key, value})
+	}
+	if m == nil {
+		m = genericMap{}
+	}
+	return m, nil
+}
+
+func (d *cborDecoder) decodeTagged(tag uint64, depth int) (any, error) {
+	value, err := d.decode(depth + 1)
+	if err != nil {
+		return nil, err
+	}
+	if value == cborBreak {
+		return nil, fmt.Errorf("%w: cbor: unexpected break", ErrMalformedRequest)
+	}
+	switch tag {
+	case cborTagDateTime:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: cbor: date/time must be a string", ErrMalformedRequest)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("cbor: invalid date/time %q", s)
+		}
+		return t.UTC(), nil
+	case cborTagEpoch:
+		if sec, ok := genericInt(value); ok {
+			return time.Unix(sec, 0).UTC(), nil
+		}
+		if f, ok := genericFloat(value); ok {
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+		return nil, fmt.Errorf("%w: cbor: epoch-based date/time must be a number", ErrMalformedRequest)
+	}
+	return value, nil
+}
+
+// halfToFloat32 converts an IEEE 754 half-precision float to float32.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		// Zero or subnormal: frac * 2^-24.
+		f := float32(frac) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13) // Inf or NaN
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | frac<<13)
+}
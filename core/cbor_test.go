@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCBOR_Values(t *testing.T) {
+	// The examples of RFC 8949, Appendix A.
+	for _, c := range []struct {
+		value any
+		hex   string
+	}{
+		{int64(0), "00"},
+		{int64(23), "17"},
+		{int64(24), "1818"},
+		{int64(1000), "1903e8"},
+		{int64(1000000000000), "1b000000e8d4a51000"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{int64(-1), "20"},
+		{int64(-1000), "3903e7"},
+		{1.1, "fb3ff199999999999a"},
+		{float32(100000.0), "fa47c35000"},
+		{false, "f4"},
+		{true, "f5"},
+		{nil, "f6"},
+		{[]byte{1, 2, 3, 4}, "4401020304"},
+		{"", "60"},
+		{"IETF", "6449455446"},
+		{"ü", "62c3bc"},
+		{[]any{}, "80"},
+		{[]any{int64(1), []any{int64(2), int64(3)}}, "8201820203"},
+		{genericMap{}, "a0"},
+		{genericMap{{"a", int64(1)}, {"b", []any{int64(2), int64(3)}}}, "a26161016162820203"},
+		{time.Date(2013, 3, 21, 20, 4, 0, 0, time.UTC), "c074323031332d30332d32315432303a30343a30305a"},
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, encodeCBOR(&buf, c.value))
+		assert.Equal(t, c.hex, hex.EncodeToString(buf.Bytes()), c.value)
+
+		data, _ := hex.DecodeString(c.hex)
+		got, err := (&cborDecoder{data: data}).decode(0)
+		assert.NoError(t, err)
+		assert.Equal(t, c.value, got, c.hex)
+	}
+}
+
+func TestCBOR_DecodeOnly(t *testing.T) {
+	for _, c := range []struct {
+		hex   string
+		value any
+	}{
+		{"f93c00", float32(1.0)},
+		{"f9c400", float32(-4.0)},
+		{"f90001", float32(5.960464477539063e-8)},
+		{"f97c00", float32(math.Inf(1))},
+		{"f7", nil},                                      // undefined
+		{"c11a514b67b0", time.Unix(1363896240, 0).UTC()}, // epoch-based date/time
+		{"c1fb41d452d9ec200000", time.Unix(1363896240, 500000000).UTC()},
+		{"d74401020304", []byte{1, 2, 3, 4}},          // unknown tag
+		{"5f42010243030405ff", []byte{1, 2, 3, 4, 5}}, // indefinite-length bytes
+		{"7f657374726561646d696e67ff", "streaming"},   // indefinite-length text
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"bf61610161629f0203ffff", genericMap{{"a", int64(1)}, {"b", []any{int64(2), int64(3)}}}},
+	} {
+		data, _ := hex.DecodeString(c.hex)
+		got, err := (&cborDecoder{data: data}).decode(0)
+		assert.NoError(t, err, c.hex)
+		assert.Equal(t, c.value, got, c.hex)
+	}
+}
+
+func TestCBOR_Malformed(t *testing.T) {
+	for _, data := range []string{"", "1c", "62c3", "ff", "1f", "5f01ff", "bf6161ff", "9bffffffffffffffff"} {
+		var v any
+		b, _ := hex.DecodeString(data)
+		err := (&CBORBody{}).Decode(bytes.NewReader(b), &v)
+		assert.Error(t, err, data)
+		assert.Equal(t, 400, DefaultErrorStatusMapper(classifyRequestError(err)), data)
+	}
+}
+
+type CBORTelemetryInput struct {
+	Payload *TelemetryPayload `in:"body=auto"`
+}
+
+func TestCBOR_Body(t *testing.T) {
+	co, err := New(CBORTelemetryInput{})
+	assert.NoError(t, err)
+
+	var body bytes.Buffer
+	input := &CBORTelemetryInput{Payload: sampleTelemetryPayload()}
+	reader, err := (&CBORBody{}).Encode(input.Payload)
+	assert.NoError(t, err)
+	_, _ = body.ReadFrom(reader)
+
+	req, _ := http.NewRequest("POST", "/telemetry", &body)
+	req.Header.Set("Content-Type", "application/cbor")
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, input, got)
+}
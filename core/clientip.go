@@ -0,0 +1,195 @@
+// directive: "clientip"
+// https://ggicci.github.io/httpin/directives/clientip
+
+package core
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DirectiveClientIP implements the "clientip" executor who extracts the IP
+// address of the client. The forwarding header, X-Forwarded-For by default, is
+// only consulted when the request comes from a trusted proxy, see
+// WithTrustedProxies and WithTrustedProxyHeader. Otherwise the IP address is
+// taken from http.Request.RemoteAddr.
+//
+// The field can be of type string, netip.Addr, or any other type that can be
+// decoded from the string representation of an IP address.
+type DirectiveClientIP struct{}
+
+func (*DirectiveClientIP) Decode(rtm *DirectiveRuntime) error {
+	req := rtm.GetRequest()
+	trustedProxies, _ := rtm.Context.Value(CtxTrustedProxies).([]netip.Prefix)
+	header, _ := rtm.Context.Value(ctxTrustedProxyHeader).(string)
+	values := make(map[string][]string)
+	if ip, ok := resolveClientIP(req, trustedProxies, header); ok {
+		values["clientip"] = []string{ip.String()}
+	}
+	extractor := &FormExtractor{
+		Runtime: rtm,
+		Form: multipart.Form{
+			Value: values,
+		},
+	}
+	return extractor.Extract("clientip")
+}
+
+// Encode does nothing. The client IP is determined by the network, not by the
+// content of the request.
+func (*DirectiveClientIP) Encode(rtm *DirectiveRuntime) error {
+	return nil
+}
+
+const defaultTrustedProxyHeader = "X-Forwarded-For"
+
+func isForwardingHeader(header string) bool {
+	return header == "Forwarded" || header == "X-Forwarded-For" || header == "X-Real-IP"
+}
+
+// resolveClientIP walks the forwarding chain in the given header from the
+// nearest hop (the remote peer) to the farthest one. The first hop which is
+// not a trusted proxy is the client. When all the hops are trusted, the
+// farthest one is the client. When a hop can't be parsed, e.g. it's an
+// obfuscated identifier like "unknown" or "_hidden", the last trusted hop is
+// the client. Returns false only if the remote address can't be parsed.
+func resolveClientIP(r *http.Request, trustedProxies []netip.Prefix, header string) (netip.Addr, bool) {
+	remote, ok := parseHopIP(r.RemoteAddr)
+	if !ok {
+		return netip.Addr{}, false
+	}
+	if !isTrustedProxy(remote, trustedProxies) {
+		return remote, true
+	}
+
+	var hops []string
+	switch header {
+	case "Forwarded":
+		hops = parseForwardedFor(r.Header.Values("Forwarded"))
+	case "X-Real-IP":
+		if value := r.Header.Get("X-Real-IP"); value != "" {
+			hops = []string{value}
+		}
+	default:
+		for _, value := range r.Header.Values(defaultTrustedProxyHeader) {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	}
+
+	last := remote // the last trusted hop
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, ok := parseHopIP(hops[i])
+		if !ok {
+			return last, true
+		}
+		if i == 0 || !isTrustedProxy(ip, trustedProxies) {
+			return ip, true
+		}
+		last = ip
+	}
+	return remote, true
+}
+
+func isTrustedProxy(ip netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHopIP parses the IP address of a hop. The port is optional. e.g.
+// "192.0.2.1", "192.0.2.1:8080", "2001:db8::1", "[2001:db8::1]:8080".
+func parseHopIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if ip, err := netip.ParseAddr(s); err == nil {
+		return ip.Unmap(), true
+	}
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		if ip, err := netip.ParseAddr(s[1 : len(s)-1]); err == nil {
+			return ip.Unmap(), true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// parseForwardedFor returns the values of the "for" parameters in the
+// Forwarded headers, in the order of the hops. See RFC 7239, Section 4.
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]:4711"
+func parseForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			for _, pair := range splitQuoted(element, ';') {
+				k, v, found := strings.Cut(pair, "=")
+				if found && strings.EqualFold(strings.TrimSpace(k), "for") {
+					hops = append(hops, unquote(strings.TrimSpace(v)))
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// splitQuoted splits s by sep, the separators inside quoted strings are ignored.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts    []string
+		start    int
+		inQuotes bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case inQuotes && s[i] == '\\':
+			i++ // skip the escaped character
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote removes the quotes of a quoted-string and unescapes the
+// quoted-pairs in it. See RFC 9110, Section 5.6.4.
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	s = s[1 : len(s)-1]
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
+
+// parseTrustedProxy parses a trusted proxy, which is an IP address or a CIDR.
+func parseTrustedProxy(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		return prefix.Masked(), nil
+	}
+	ip, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+	}
+	ip = ip.Unmap()
+	return netip.PrefixFrom(ip, ip.BitLen()), nil
+}
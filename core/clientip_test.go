@@ -0,0 +1,168 @@
+package core
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectiveClientIP_Decode(t *testing.T) {
+	type Input struct {
+		IP   netip.Addr `in:"clientip"`
+		IPv4 string     `in:"clientip"`
+	}
+
+	testcases := []struct {
+		name          string
+		trustedHeader string // X-Forwarded-For if empty
+		remoteAddr    string
+		header        http.Header
+		expected      string
+	}{
+		{
+			name:       "untrusted remote ignores headers",
+			remoteAddr: "192.0.2.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"198.51.100.7"}},
+			expected:   "192.0.2.1",
+		},
+		{
+			name:       "trusted remote without headers",
+			remoteAddr: "10.0.0.1:1234",
+			expected:   "10.0.0.1",
+		},
+		{
+			name:       "x-forwarded-for",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.5, 198.51.100.7, 10.0.0.2"}},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "x-forwarded-for in multiple lines",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"203.0.113.5", "198.51.100.7", "10.0.0.2"}},
+			expected:   "198.51.100.7",
+		},
+		{
+			name:       "x-forwarded-for all trusted",
+			remoteAddr: "10.0.0.1:1234",
+			header:     http.Header{"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"}},
+			expected:   "10.0.0.3",
+		},
+		{
+			name:       "x-forwarded-for ignores the other headers",
+			remoteAddr: "10.0.0.1:1234",
+			header: http.Header{
+				"Forwarded":       {"for=1.2.3.4"}, // sent by the client
+				"X-Real-Ip":       {"1.2.3.4"},
+				"X-Forwarded-For": {"198.51.100.7"},
+			},
+			expected: "198.51.100.7",
+		},
+		{
+			name:          "x-real-ip",
+			trustedHeader: "x-real-ip",
+			remoteAddr:    "10.0.0.1:1234",
+			header:        http.Header{"X-Real-Ip": {"198.51.100.7"}},
+			expected:      "198.51.100.7",
+		},
+		{
+			name:          "forwarded",
+			trustedHeader: "Forwarded",
+			remoteAddr:    "[2001:db8::1]:443",
+			header: http.Header{
+				"Forwarded":       {`for=192.0.2.60;proto=http;by=203.0.113.43, For="[2001:db8:cafe::17]:4711"`},
+				"X-Forwarded-For": {"198.51.100.7"}, // ignored, Forwarded is trusted
+			},
+			expected: "2001:db8:cafe::17",
+		},
+		{
+			name:          "forwarded with trusted hops",
+			trustedHeader: "Forwarded",
+			remoteAddr:    "10.0.0.1:1234",
+			header:        http.Header{"Forwarded": {`for=192.0.2.60, for=10.1.2.3`, `for="10.0.0.2:80"`}},
+			expected:      "192.0.2.60",
+		},
+		{
+			name:          "forwarded with ipv4-mapped ipv6 address",
+			trustedHeader: "Forwarded",
+			remoteAddr:    "[::ffff:10.0.0.1]:1234",
+			header:        http.Header{"Forwarded": {`for="[::ffff:192.0.2.60]"`}},
+			expected:      "192.0.2.60",
+		},
+		{
+			name:       "remote address without port",
+			remoteAddr: "192.0.2.1",
+			expected:   "192.0.2.1",
+		},
+	}
+
+	for _, c := range testcases {
+		t.Run(c.name, func(t *testing.T) {
+			opts := []Option{WithTrustedProxies("10.0.0.0/8", "2001:db8::1")}
+			if c.trustedHeader != "" {
+				opts = append(opts, WithTrustedProxyHeader(c.trustedHeader))
+			}
+			co, err := New(Input{}, opts...)
+			assert.NoError(t, err)
+
+			r, _ := http.NewRequest("GET", "/", nil)
+			r.RemoteAddr = c.remoteAddr
+			if c.header != nil {
+				r.Header = c.header
+			}
+			got, err := co.Decode(r)
+			assert.NoError(t, err)
+			assert.Equal(t, &Input{
+				IP:   netip.MustParseAddr(c.expected),
+				IPv4: c.expected,
+			}, got)
+		})
+	}
+}
+
+func TestDirectiveClientIP_Decode_UnparseableHop(t *testing.T) {
+	type Input struct {
+		IP netip.Addr `in:"clientip;required"`
+	}
+
+	co, err := New(Input{}, WithTrustedProxies("10.0.0.0/8"), WithTrustedProxyHeader("Forwarded"))
+	assert.NoError(t, err)
+
+	// Obfuscated identifier, falls back to the last trusted hop.
+	for header, expected := range map[string]string{
+		"for=unknown":                "10.0.0.1",
+		"for=_hidden, for=10.0.0.2":  "10.0.0.2",
+		"for=192.0.2.9, for=_hidden": "10.0.0.1",
+	} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("Forwarded", header)
+		got, err := co.Decode(r)
+		assert.NoError(t, err)
+		assert.Equal(t, netip.MustParseAddr(expected), got.(*Input).IP, header)
+	}
+
+	// Spoofed header from untrusted client is ignored.
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("Forwarded", "for=unknown")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, netip.MustParseAddr("192.0.2.1"), got.(*Input).IP)
+}
+
+func TestDirectiveClientIP_NewRequest(t *testing.T) {
+	type Input struct {
+		IP   string `in:"clientip"`
+		Page int    `in:"query=page"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+	req, err := co.NewRequest("GET", "/", &Input{IP: "192.0.2.1", Page: 1})
+	assert.NoError(t, err)
+	expected, _ := http.NewRequest("GET", "/?page=1", nil)
+	assert.Equal(t, expected, req)
+}
@@ -0,0 +1,71 @@
+// directive: "cookie"
+// https://ggicci.github.io/httpin/directives/cookie
+
+package core
+
+import (
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+type DirectiveCookie struct{}
+
+// Decode implements the "cookie" executor who extracts values from
+// the cookies of an HTTP request.
+func (*DirectiveCookie) Decode(rtm *DirectiveRuntime) error {
+	req := rtm.GetRequest()
+	values := make(map[string][]string)
+	for _, name := range rtm.Directive.Argv {
+		if prefix, ok := wildcardPrefix(name); ok {
+			for _, cookie := range req.Cookies() {
+				if strings.HasPrefix(cookie.Name, prefix) {
+					values[cookie.Name] = append(values[cookie.Name], cookie.Value)
+				}
+			}
+			continue
+		}
+		for _, cookie := range req.CookiesNamed(name) {
+			values[name] = append(values[name], cookie.Value)
+		}
+	}
+	extractor := &FormExtractor{
+		Runtime: rtm,
+		Form: multipart.Form{
+			Value: values,
+		},
+	}
+	return extractor.Extract()
+}
+
+// Encode implements the "cookie" executor who adds the values as cookies to
+// the request. A value which can't be sent as is, i.e. http.Cookie.Valid fails
+// on it, e.g. containing ';', '"' or '\', results in an error, rather than
+// being sanitized by net/http.
+func (*DirectiveCookie) Encode(rtm *DirectiveRuntime) error {
+	rb := rtm.GetRequestBuilder()
+	var invalid error
+	encoder := &FormEncoder{
+		Setter: func(key string, value []string) {
+			if invalid == nil {
+				if invalid = validateCookie(key, value); invalid == nil {
+					rb.SetCookie(key, value)
+				}
+			}
+		},
+	}
+	if err := encoder.Execute(rtm); err != nil {
+		return err
+	}
+	return invalid
+}
+
+func validateCookie(name string, values []string) error {
+	for _, value := range values {
+		if err := (&http.Cookie{Name: name, Value: value}).Valid(); err != nil {
+			return fmt.Errorf("invalid cookie %q: %w", name, err)
+		}
+	}
+	return nil
+}
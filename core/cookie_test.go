@@ -0,0 +1,116 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectiveCookie_Decode(t *testing.T) {
+	type SessionInput struct {
+		SessionID string                `in:"cookie=session_id;required"`
+		Theme     string                `in:"cookie=theme;default=light"`
+		Visits    patch.Field[int]      `in:"cookie=visits"`
+		Tags      []string              `in:"cookie=tag"`
+		Place     *Place                `in:"cookie=place"`
+		Missing   patch.Field[[]string] `in:"cookie=missing"`
+	}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session_id", Value: "abc123"})
+	r.AddCookie(&http.Cookie{Name: "visits", Value: "7"})
+	r.AddCookie(&http.Cookie{Name: "tag", Value: "a"})
+	r.AddCookie(&http.Cookie{Name: "tag", Value: "b"})
+	r.AddCookie(&http.Cookie{Name: "place", Value: "Canada.Toronto"})
+
+	co, err := New(SessionInput{})
+	assert.NoError(t, err)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &SessionInput{
+		SessionID: "abc123",
+		Theme:     "light",
+		Visits:    patch.Field[int]{Value: 7, Valid: true},
+		Tags:      []string{"a", "b"},
+		Place:     &Place{Country: "Canada", City: "Toronto"},
+	}, got)
+}
+
+func TestDirectiveCookie_Decode_Errors(t *testing.T) {
+	type SessionInput struct {
+		SessionID string `in:"cookie=session_id;required"`
+		Visits    int    `in:"cookie=visits"`
+	}
+
+	co, err := New(SessionInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	_, err = co.Decode(r)
+	assert.ErrorContains(t, err, "missing required field")
+
+	r.AddCookie(&http.Cookie{Name: "session_id", Value: "abc123"})
+	r.AddCookie(&http.Cookie{Name: "visits", Value: "many"})
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Visits", invalidField.Field)
+	assert.Equal(t, "cookie", invalidField.Directive)
+	assert.Equal(t, "visits", invalidField.Key)
+	assert.Equal(t, []string{"many"}, invalidField.Value)
+}
+
+func TestDirectiveCookie_NewRequest(t *testing.T) {
+	type SessionInput struct {
+		SessionID string   `in:"cookie=session_id"`
+		Visits    int      `in:"cookie=visits;omitempty"`
+		Tags      []string `in:"cookie=tag"`
+		Theme     *string  `in:"cookie=theme;omitempty"`
+	}
+
+	co, err := New(SessionInput{})
+	assert.NoError(t, err)
+	req, err := co.NewRequest("GET", "/", &SessionInput{
+		SessionID: "abc123",
+		Tags:      []string{"a", "b"},
+	})
+	assert.NoError(t, err)
+
+	expected, _ := http.NewRequest("GET", "/", nil)
+	expected.AddCookie(&http.Cookie{Name: "session_id", Value: "abc123"})
+	expected.AddCookie(&http.Cookie{Name: "tag", Value: "a"})
+	expected.AddCookie(&http.Cookie{Name: "tag", Value: "b"})
+	assert.Equal(t, expected, req)
+
+	// Round trip.
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, &SessionInput{
+		SessionID: "abc123",
+		Tags:      []string{"a", "b"},
+	}, got)
+}
+
+func TestDirectiveCookie_NewRequest_InvalidValue(t *testing.T) {
+	type SessionInput struct {
+		S string `in:"cookie=s"`
+	}
+	co, err := New(SessionInput{})
+	assert.NoError(t, err)
+
+	for _, value := range []string{`hello world; x="y"`, `a;b`, `"quoted"`, `back\slash`} {
+		_, err := co.NewRequest("GET", "/", &SessionInput{S: value})
+		assert.ErrorContains(t, err, `invalid cookie "s"`, value)
+	}
+
+	// The values accepted round-trip exactly.
+	for _, value := range []string{"hello world", "a,b", "x=y"} {
+		req, err := co.NewRequest("GET", "/", &SessionInput{S: value})
+		assert.NoError(t, err, value)
+		got, err := co.Decode(req)
+		assert.NoError(t, err, value)
+		assert.Equal(t, value, got.(*SessionInput).S)
+	}
+}
@@ -1,11 +1,14 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"mime"
 	"net/http"
+	"net/netip"
 	"reflect"
 	"sort"
 	"sync"
@@ -30,6 +33,12 @@ type Core struct {
 	errorHandler           ErrorHandler
 	maxMemory              int64 // in bytes
 	enableNestedDirectives bool
+	trustedProxies         []netip.Prefix
+	trustedProxyHeader     string
+	bodyReplayMaxBytes     int64 // 0 means disabled
+	collectAllErrors       bool
+	errorStatusMapper      ErrorStatusMapper
+	maxBodyBytes           int64 // 0 means unlimited
 	resolverMu             sync.RWMutex
 }
 
@@ -82,22 +91,50 @@ func (c *Core) Decode(req *http.Request) (any, error) {
 }
 
 // DecodeTo decodes an HTTP request to the given value. The value must be a pointer
-// to the struct instance of the type that the Core instance holds.
+// to the struct instance of the type that the Core instance holds. After all the
+// directives have run, the value is validated if it implements Validator or
+// ContextValidator.
 func (c *Core) DecodeTo(req *http.Request, value any) (err error) {
+	cache := &requestCache{}
+	if c.maxBodyBytes > 0 && req.Body != nil && req.Body != http.NoBody {
+		req.Body = http.MaxBytesReader(nil, req.Body, c.maxBodyBytes)
+	}
+	if c.bodyReplayMaxBytes > 0 {
+		restore, err := c.bufferRequestBody(req, cache)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
 	if err = c.parseRequestForm(req); err != nil {
-		return fmt.Errorf("%w: %w", ErrFailedToParseRequestForm, err)
+		return fmt.Errorf("%w: %w", ErrFailedToParseRequestForm, classifyRequestError(err))
 	}
 
+	var collector *errorCollector
+	if c.collectAllErrors {
+		collector = &errorCollector{}
+	}
 	err = c.resolver.ResolveTo(
 		value,
 		owl.WithNamespace(decoderNamespace),
 		owl.WithValue(CtxRequest, req),
+		owl.WithValue(CtxTrustedProxies, c.trustedProxies),
+		owl.WithValue(ctxTrustedProxyHeader, c.trustedProxyHeader),
+		owl.WithValue(ctxRequestCache, cache),
+		owl.WithValue(ctxErrorCollector, collector),
 		owl.WithNestedDirectivesEnabled(c.enableNestedDirectives),
 	)
 	if err != nil && !errors.Is(err, owl.ErrInvalidResolveTarget) {
 		return NewInvalidFieldError(err)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	if collector != nil && len(collector.errors) > 0 {
+		return collector.errors
+	}
+	return validateInput(req.Context(), value)
 }
 
 // NewRequest wraps NewRequestWithContext using context.Background(), see
@@ -150,13 +187,22 @@ func (c *Core) NewRequestWithContext(ctx context.Context, method string, url str
 }
 
 // GetErrorHandler returns the error handler of the core if set, or the global
-// custom error handler.
+// custom error handler. When a custom ErrorStatusMapper is set by
+// WithErrorStatusMapper, the returned handler makes it available to
+// ErrorStatusCode through the context of the request.
 func (c *Core) GetErrorHandler() ErrorHandler {
-	if c.errorHandler != nil {
-		return c.errorHandler
+	handler := c.errorHandler
+	if handler == nil {
+		handler = globalCustomErrorHandler
+	}
+	if c.errorStatusMapper == nil {
+		return handler
 	}
 
-	return globalCustomErrorHandler
+	mapper := c.errorStatusMapper
+	return func(rw http.ResponseWriter, r *http.Request, err error) {
+		handler(rw, r.WithContext(context.WithValue(r.Context(), ctxErrorStatusMapper, mapper)), err)
+	}
 }
 
 func (c *Core) prepareScanResolver() {
@@ -180,6 +226,43 @@ func (c *Core) prepareScanResolver() {
 	}
 }
 
+// bufferRequestBody reads the request body into memory if its size does not
+// exceed the limit of body replay (see WithBodyReplay), and saves it to the
+// cache. So that the body can be read by multiple directives. The returned
+// function restores the request body, which should be called after decoding.
+// When the body is too large, it won't be replayable, and no error occurs.
+func (c *Core) bufferRequestBody(req *http.Request, cache *requestCache) (func(), error) {
+	noop := func() {}
+	if req.Body == nil || req.Body == http.NoBody {
+		return noop, nil
+	}
+
+	original := req.Body
+	body, err := io.ReadAll(io.LimitReader(original, c.bodyReplayMaxBytes+1))
+	if err != nil {
+		return noop, fmt.Errorf("failed to read request body: %w", classifyRequestError(err))
+	}
+
+	if int64(len(body)) > c.bodyReplayMaxBytes {
+		// Too large to replay, put back the bytes read.
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return noop, nil
+	}
+
+	cache.body = body
+	cache.bodyRead = true
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return func() {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}, nil
+}
+
 func (c *Core) parseRequestForm(req *http.Request) (err error) {
 	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
 	if ct == "multipart/form-data" {
@@ -220,6 +303,18 @@ func normalizeResolver(r *owl.Resolver) error {
 		for _, fn := range []func(*owl.Resolver) error{
 			removeDecoderDirective,             // backward compatibility, use "coder" instead
 			removeCoderDirective,               // "coder" takes precedence over "decoder"
+			applyHTTPDateCoder,                 // HTTP-date coder for time.Time headers
+			reserveStyleDirective,              // "style", "explode" and "delim"
+			reserveTransformDirectives,         // after reserveStyleDirective
+			compileValidationDirectives,        // "min", "max", "pattern", etc.
+			reserveBodyOptionDirectives,        // "strict", "usenumber", etc.
+			validateBodyDirective,              // "raw" and "text" body formats
+			validateStreamBodyDirective,        // "ndjson" and stream fields
+			validateWildcardKeys,               // map fields of wildcard keys
+			validateCtxDirective,               // "ctx"
+			validateRequestDirective,           // "request"
+			validateAuthDirective,              // "auth"
+			validateJSONPointerDirective,       // "jsonptr"
 			ensureDirectiveExecutorsRegistered, // always the last one
 		} {
 			if err := fn(r); err != nil {
@@ -0,0 +1,175 @@
+// directive: "ctx"
+// https://ggicci.github.io/httpin/directives/ctx
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/ggicci/httpin/internal"
+	"github.com/ggicci/owl"
+)
+
+var (
+	ErrUnregisteredContextKey = errors.New("unregistered context key")
+
+	contextKeys = make(map[string]*NamedContextKey)
+)
+
+// NamedContextKey is a key of the request context bound to a name. The name is
+// used by the "ctx" directive to locate the value in the request context.
+type NamedContextKey struct {
+	Name      string
+	Key       any
+	ValueType reflect.Type // the type of the value stored in the context
+}
+
+// RegisterContextKey binds the given name to a key of the request context, T
+// is the type of the value stored under the key. It's useful when an upstream
+// middleware puts values into the request context, e.g. the authenticated
+// principal, the tenant ID, etc. Use the "ctx" directive to bind the values to
+// the fields of the input struct. For example:
+//
+//	type tenantKey struct{}
+//
+//	func init() {
+//		core.RegisterContextKey[string]("tenant", tenantKey{})
+//	}
+//
+//	type ListUsersInput struct {
+//		Tenant string `in:"ctx=tenant"`
+//	}
+//
+// The type of the field is checked against T when creating the Core instance.
+// Panics on taken name, empty name or nil key. Pass parameter force (true) to
+// ignore the name conflict.
+func RegisterContextKey[T any](name string, key any, force ...bool) {
+	internal.PanicOnError(
+		registerContextKey(name, key, internal.TypeOf[T](), force...),
+	)
+}
+
+func registerContextKey(name string, key any, valueType reflect.Type, force ...bool) error {
+	ignoreConflict := len(force) > 0 && force[0]
+	if name == "" {
+		return errors.New("context key name cannot be empty")
+	}
+	if key == nil {
+		return errors.New("context key cannot be nil")
+	}
+	if !ignoreConflict && contextKeys[name] != nil {
+		return fmt.Errorf("duplicate context key: %q", name)
+	}
+	contextKeys[name] = &NamedContextKey{
+		Name:      name,
+		Key:       key,
+		ValueType: valueType,
+	}
+	return nil
+}
+
+// DirectiveCtx implements the "ctx" executor who extracts values from the
+// context of an HTTP request. The keys of the context must be registered by
+// RegisterContextKey beforehand.
+type DirectiveCtx struct{}
+
+func (*DirectiveCtx) Decode(rtm *DirectiveRuntime) error {
+	if rtm.IsFieldSet() {
+		return nil // skip when already extracted by former directives
+	}
+
+	ctx := rtm.GetRequest().Context()
+	for _, name := range rtm.Directive.Argv {
+		ck := contextKeys[name]
+		if ck == nil {
+			return fmt.Errorf("%w: %q", ErrUnregisteredContextKey, name)
+		}
+		value := ctx.Value(ck.Key)
+		if value == nil {
+			continue
+		}
+		if err := setContextValue(rtm.Value.Elem(), value); err != nil {
+			return &fieldError{name, nil, err}
+		}
+		rtm.MarkFieldSet(true)
+		return nil
+	}
+	return nil
+}
+
+// Encode puts the value of the field into the context of the HTTP request.
+func (*DirectiveCtx) Encode(rtm *DirectiveRuntime) error {
+	if rtm.IsFieldSet() {
+		return nil // skip when already encoded by former directives
+	}
+
+	value := rtm.Value
+	if IsPatchField(value.Type()) {
+		if !value.FieldByName("Valid").Bool() {
+			return nil
+		}
+		value = value.FieldByName("Value")
+	}
+	if value.IsZero() {
+		return nil
+	}
+
+	ck := contextKeys[rtm.Directive.Argv[0]]
+	if ck == nil {
+		return fmt.Errorf("%w: %q", ErrUnregisteredContextKey, rtm.Directive.Argv[0])
+	}
+	rtm.GetRequestBuilder().SetContextValue(ck.Key, value.Interface())
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+func setContextValue(fv reflect.Value, value any) error {
+	if IsPatchField(fv.Type()) {
+		if err := setContextValue(fv.FieldByName("Value"), value); err != nil {
+			return err
+		}
+		fv.FieldByName("Valid").SetBool(true)
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if !rv.Type().AssignableTo(fv.Type()) {
+		return fmt.Errorf("%w: value of type %q is not assignable to type %q",
+			ErrTypeMismatch, rv.Type(), fv.Type())
+	}
+	fv.Set(rv)
+	return nil
+}
+
+// validateCtxDirective ensures that the context keys referenced by the "ctx"
+// directive are registered, and their value types fit the field type.
+func validateCtxDirective(r *owl.Resolver) error {
+	d := r.GetDirective("ctx")
+	if d == nil {
+		return nil
+	}
+	if len(d.Argv) == 0 {
+		return errors.New("directive ctx: missing context key name")
+	}
+
+	fieldType := r.Type
+	if IsPatchField(fieldType) {
+		valueField, _ := fieldType.FieldByName("Value")
+		fieldType = valueField.Type
+	}
+	for _, name := range d.Argv {
+		ck := contextKeys[name]
+		if ck == nil {
+			return fmt.Errorf("directive ctx: %w: %q", ErrUnregisteredContextKey, name)
+		}
+		// When the value type is an interface, the concrete type of the value
+		// can only be checked at runtime.
+		if ck.ValueType.Kind() != reflect.Interface && !ck.ValueType.AssignableTo(fieldType) {
+			return fmt.Errorf("directive ctx: %w: value of context key %q is of type %q, not assignable to type %q",
+				ErrTypeMismatch, name, ck.ValueType, fieldType)
+		}
+	}
+	return nil
+}
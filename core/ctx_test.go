@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxTestKey int
+
+const (
+	ctxTestKeyTenant ctxTestKey = iota
+	ctxTestKeyPrincipal
+	ctxTestKeyTraceID
+	ctxTestKeyAny
+)
+
+type Principal struct {
+	ID   int
+	Name string
+}
+
+func init() {
+	RegisterContextKey[string]("test_tenant", ctxTestKeyTenant)
+	RegisterContextKey[*Principal]("test_principal", ctxTestKeyPrincipal)
+	RegisterContextKey[string]("test_trace_id", ctxTestKeyTraceID)
+	RegisterContextKey[any]("test_any", ctxTestKeyAny)
+}
+
+func TestRegisterContextKey(t *testing.T) {
+	assert.PanicsWithError(t, "httpin: duplicate context key: \"test_tenant\"", func() {
+		RegisterContextKey[string]("test_tenant", ctxTestKeyTenant)
+	})
+	assert.PanicsWithError(t, "httpin: context key name cannot be empty", func() {
+		RegisterContextKey[string]("", ctxTestKeyTenant)
+	})
+	assert.PanicsWithError(t, "httpin: context key cannot be nil", func() {
+		RegisterContextKey[string]("test_nil", nil)
+	})
+	assert.NotPanics(t, func() {
+		RegisterContextKey[string]("test_tenant", ctxTestKeyTenant, true)
+	})
+}
+
+func TestDirectiveCtx_Decode(t *testing.T) {
+	type Input struct {
+		Tenant    string              `in:"ctx=test_tenant;required"`
+		Principal *Principal          `in:"ctx=test_principal"`
+		TraceID   patch.Field[string] `in:"ctx=test_trace_id"`
+		Any       any                 `in:"ctx=test_any"`
+		Page      int                 `in:"query=page"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	principal := &Principal{ID: 1, Name: "ggicci"}
+	ctx := context.WithValue(context.Background(), ctxTestKeyTenant, "acme")
+	ctx = context.WithValue(ctx, ctxTestKeyPrincipal, principal)
+	ctx = context.WithValue(ctx, ctxTestKeyTraceID, "trace-1")
+	ctx = context.WithValue(ctx, ctxTestKeyAny, 3.14)
+	r, _ := http.NewRequestWithContext(ctx, "GET", "/?page=2", nil)
+
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Input{
+		Tenant:    "acme",
+		Principal: principal,
+		TraceID:   patch.Field[string]{Value: "trace-1", Valid: true},
+		Any:       3.14,
+		Page:      2,
+	}, got)
+
+	// Missing values.
+	r, _ = http.NewRequest("GET", "/", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Tenant", invalidField.Field)
+	assert.Equal(t, "required", invalidField.Directive)
+}
+
+func TestDirectiveCtx_Decode_ErrTypeMismatchAtRuntime(t *testing.T) {
+	type Input struct {
+		Tenant string `in:"ctx=test_any"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	ctx := context.WithValue(context.Background(), ctxTestKeyAny, 100)
+	r, _ := http.NewRequestWithContext(ctx, "GET", "/", nil)
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "test_any", invalidField.Key)
+	assert.Nil(t, invalidField.Value)
+}
+
+func TestDirectiveCtx_New_Errors(t *testing.T) {
+	type InputUnregistered struct {
+		Tenant string `in:"ctx=test_unregistered"`
+	}
+	_, err := New(InputUnregistered{})
+	assert.ErrorIs(t, err, ErrUnregisteredContextKey)
+
+	type InputMissingName struct {
+		Tenant string `in:"ctx"`
+	}
+	_, err = New(InputMissingName{})
+	assert.ErrorContains(t, err, "missing context key name")
+
+	type InputTypeMismatch struct {
+		Tenant int `in:"ctx=test_tenant"`
+	}
+	_, err = New(InputTypeMismatch{})
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	assert.ErrorContains(t, err, "test_tenant")
+}
+
+func TestDirectiveCtx_NewRequest(t *testing.T) {
+	type Input struct {
+		Tenant    string              `in:"ctx=test_tenant;required"`
+		Principal *Principal          `in:"ctx=test_principal"`
+		TraceID   patch.Field[string] `in:"ctx=test_trace_id"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	principal := &Principal{ID: 1, Name: "ggicci"}
+	req, err := co.NewRequest("GET", "/", &Input{
+		Tenant:    "acme",
+		Principal: principal,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", req.Context().Value(ctxTestKeyTenant))
+	assert.Equal(t, principal, req.Context().Value(ctxTestKeyPrincipal))
+	assert.Nil(t, req.Context().Value(ctxTestKeyTraceID))
+
+	// Round trip.
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, &Input{Tenant: "acme", Principal: principal}, got)
+
+	// The required directive fails when the value is missing.
+	_, err = co.NewRequest("GET", "/", &Input{})
+	assert.ErrorContains(t, err, "missing required field")
+}
@@ -12,10 +12,19 @@ func init() {
 	RegisterDirective("form", &DirectvieForm{})
 	RegisterDirective("query", &DirectiveQuery{})
 	RegisterDirective("header", &DirectiveHeader{})
+	RegisterDirective("cookie", &DirectiveCookie{})
+	RegisterDirective("ctx", &DirectiveCtx{})
+	RegisterDirective("request", &DirectiveRequest{})
+	RegisterDirective("clientip", &DirectiveClientIP{})
+	RegisterDirective("auth", &DirectiveAuth{})
 	RegisterDirective("body", &DirectiveBody{})
+	RegisterDirective("jsonptr", &DirectiveJSONPointer{})
 	RegisterDirective("required", &DirectiveRequired{})
 	RegisterDirective("default", &DirectiveDefault{})
 	RegisterDirective("nonzero", &DirectiveNonzero{})
+	for _, name := range validationDirectives {
+		RegisterDirective(name, &DirectiveValidation{})
+	}
 	registerDirective("path", defaultPathDirective)
 	registerDirective("omitempty", &DirectiveOmitEmpty{})
 
@@ -35,7 +44,11 @@ var (
 	encoderNamespace = owl.NewNamespace()
 
 	// reservedExecutorNames are the names that cannot be used to register user defined directives
-	reservedExecutorNames = []string{"decoder", "coder"}
+	reservedExecutorNames = []string{
+		"decoder", "coder", "style", "explode", "delim",
+		"trim", "lower", "upper", "nfc", "nfkc", "collapse",
+		"strict", "usenumber", "maxbytes",
+	}
 
 	noopDirective = &directiveNoop{}
 )
@@ -71,7 +84,7 @@ func registerDirectiveExecutorToNamespace(ns *owl.Namespace, name string, exe Di
 		internal.PanicOnError(errors.New("nil directive executor"))
 	}
 	if ns == decoderNamespace {
-		ns.RegisterDirectiveExecutor(name, asOwlDirectiveExecutor(exe.Decode), force...)
+		ns.RegisterDirectiveExecutor(name, asOwlDirectiveExecutor(collectingErrors(exe.Decode)), force...)
 	} else {
 		ns.RegisterDirectiveExecutor(name, asOwlDirectiveExecutor(exe.Encode), force...)
 	}
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"reflect"
 
@@ -38,6 +40,50 @@ const (
 	// by a former executor, the latter executors MAY skip running by consulting
 	// this context value.
 	CtxFieldSet
+
+	// CtxTrustedProxies is the key to get the trusted proxies (of
+	// []netip.Prefix) from DirectiveRuntime.Context. See WithTrustedProxies.
+	CtxTrustedProxies
+
+	// ctxRequestCache is the key to get the requestCache (of *requestCache)
+	// from DirectiveRuntime.Context. The cache is shared by all the directives
+	// during decoding a single HTTP request.
+	ctxRequestCache
+
+	// ctxFieldStyle is the key to get the fieldStyle (of *fieldStyle) from
+	// Resolver.Context. Which is specified by the "style", "explode" and
+	// "delim" directives.
+	ctxFieldStyle
+
+	// ctxFieldTransforms is the key to get the fieldTransforms (of
+	// fieldTransforms) from Resolver.Context. Which is specified by the
+	// transform directives, e.g. "trim" and "lower".
+	ctxFieldTransforms
+
+	// ctxFieldValidators is the key to get the fieldValidators (of
+	// fieldValidators) from Resolver.Context. Which are compiled from the
+	// validation directives, e.g. "min" and "pattern".
+	ctxFieldValidators
+
+	// ctxErrorCollector is the key to get the errorCollector (of
+	// *errorCollector) from DirectiveRuntime.Context. It's nil unless
+	// WithCollectAllErrors is enabled.
+	ctxErrorCollector
+
+	// ctxErrorStatusMapper is the key to get the ErrorStatusMapper from the
+	// context of the request passed to the error handler. See
+	// WithErrorStatusMapper.
+	ctxErrorStatusMapper
+
+	// ctxBodyOptions is the key to get the bodyOptions (of *bodyOptions)
+	// from Resolver.Context. Which is specified by the "strict", "usenumber",
+	// "maxbytes" and "type" directives.
+	ctxBodyOptions
+
+	// ctxTrustedProxyHeader is the key to get the forwarding header (of
+	// string) trusted by the "clientip" directive from
+	// DirectiveRuntime.Context. See WithTrustedProxyHeader.
+	ctxTrustedProxyHeader
 )
 
 // DirectiveRuntime is the runtime of a directive execution. It wraps owl.DirectiveRuntime,
@@ -93,3 +139,53 @@ func (rtm *DirectiveRuntime) SetValue(value any) error {
 	rtm.Value.Elem().Set(newValue)
 	return nil
 }
+
+// requestCache holds the data shared by the directives during decoding a
+// single HTTP request. e.g. the request body buffered by the "jsonptr"
+// directive, which is read only once, but used by multiple fields.
+type requestCache struct {
+	body     []byte
+	bodyRead bool
+	bodyErr  error
+
+	jsonDocument any
+	jsonParsed   bool
+	jsonErr      error
+}
+
+func (rtm *DirectiveRuntime) getRequestCache() *requestCache {
+	if cache := rtm.Context.Value(ctxRequestCache); cache != nil {
+		return cache.(*requestCache)
+	}
+	return nil
+}
+
+// readRequestBody reads the whole request body once and caches it. The request
+// body is replaced with a reader of the cached content. So that it can still be
+// read by the directives running afterwards, e.g. the "body" directive.
+func (rtm *DirectiveRuntime) readRequestBody() ([]byte, error) {
+	cache := rtm.getRequestCache()
+	if cache == nil {
+		cache = &requestCache{} // no cache available, read on every call
+	}
+	if !cache.bodyRead {
+		cache.bodyRead = true
+		req := rtm.GetRequest()
+		if req.Body != nil && req.Body != http.NoBody {
+			cache.body, cache.bodyErr = io.ReadAll(req.Body)
+			cache.bodyErr = classifyRequestError(cache.bodyErr)
+			req.Body = io.NopCloser(bytes.NewReader(cache.body))
+		}
+	}
+	return cache.body, cache.bodyErr
+}
+
+// getRequestBodyReader returns a reader of the request body. If the body has
+// been buffered, e.g. by WithBodyReplay, a new reader of the buffered content
+// is returned. So that multiple directives can read the body independently.
+func (rtm *DirectiveRuntime) getRequestBodyReader() (io.Reader, error) {
+	if cache := rtm.getRequestCache(); cache != nil && cache.bodyRead {
+		return bytes.NewReader(cache.body), cache.bodyErr
+	}
+	return rtm.GetRequest().Body, nil
+}
@@ -1,8 +1,13 @@
 package core
 
 import (
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
 	"strings"
 
 	"github.com/ggicci/httpin/internal"
@@ -13,6 +18,19 @@ var (
 	ErrUnregisteredDirective = errors.New("unregistered directive")
 	ErrUnregisteredCoder     = errors.New("unregistered coder")
 	ErrTypeMismatch          = internal.ErrTypeMismatch
+
+	// ErrMalformedRequest is wrapped by the errors caused by the syntax of the
+	// request, e.g. a truncated JSON body. See DefaultErrorStatusMapper.
+	ErrMalformedRequest = errors.New("malformed request")
+
+	// ErrRequestTooLarge is wrapped by the errors caused by the size of the
+	// request, e.g. the body exceeds the limit of http.MaxBytesReader.
+	ErrRequestTooLarge = errors.New("request too large")
+
+	// ErrUnsupportedMediaType is wrapped by the errors caused by the format of
+	// the request body, e.g. the Content-Type matches none of the formats of
+	// the "body" directive.
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
 )
 
 type InvalidFieldError struct {
@@ -34,6 +52,18 @@ type InvalidFieldError struct {
 
 	// ErrorMessage is the string representation of `internalError`.
 	ErrorMessage string `json:"error"`
+
+	// Code is the stable, machine-readable code of the error, e.g. "required",
+	// "invalid_int". See CodedError.
+	Code string `json:"code,omitempty"`
+
+	// Params are the parameters of the message template of the code, e.g.
+	// {"maxlen": 10, "length": 12} of "too_long".
+	Params map[string]any `json:"params,omitempty"`
+
+	// Message is the localized message of the code, which is set by the error
+	// handlers. See LocalizeMessage.
+	Message string `json:"message,omitempty"`
 }
 
 func (e *InvalidFieldError) Error() string {
@@ -60,9 +90,12 @@ func NewInvalidFieldError(err error) *InvalidFieldError {
 		r = err.Resolver
 		de = err.AsDirectiveExecutionError()
 	default:
+		code, params := errorCodeOf(err)
 		return &InvalidFieldError{
 			err:          err,
 			ErrorMessage: err.Error(),
+			Code:         code,
+			Params:       params,
 		}
 	}
 
@@ -75,6 +108,7 @@ func NewInvalidFieldError(err error) *InvalidFieldError {
 		inputKey = fe.Key
 	}
 
+	code, params := errorCodeOf(err)
 	return &InvalidFieldError{
 		err:          err,
 		Field:        r.Field.Name,
@@ -82,6 +116,8 @@ func NewInvalidFieldError(err error) *InvalidFieldError {
 		Key:          inputKey,
 		Value:        inputValue,
 		ErrorMessage: err.Error(),
+		Code:         code,
+		Params:       params,
 	}
 }
 
@@ -123,3 +159,26 @@ func (e fieldError) Error() string {
 func (e fieldError) Unwrap() error {
 	return e.internalError
 }
+
+// classifyRequestError wraps the error of reading or parsing the request with
+// ErrRequestTooLarge or ErrMalformedRequest if it falls into either class. An
+// error already wrapping either of them is returned as is.
+func classifyRequestError(err error) error {
+	var (
+		maxBytesError   *http.MaxBytesError
+		jsonSyntaxError *json.SyntaxError
+		xmlSyntaxError  *xml.SyntaxError
+	)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrRequestTooLarge), errors.Is(err, ErrMalformedRequest):
+		return err
+	case errors.As(err, &maxBytesError), errors.Is(err, multipart.ErrMessageTooLarge):
+		return fmt.Errorf("%w: %w", ErrRequestTooLarge, err)
+	case errors.As(err, &jsonSyntaxError), errors.As(err, &xmlSyntaxError),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("%w: %w", ErrMalformedRequest, err)
+	}
+	return err
+}
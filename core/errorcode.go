@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/ggicci/httpin/internal"
+	"gopkg.in/yaml.v3"
+)
+
+// The codes of the errors produced by the built-in directives and coders. They
+// are stable, so that the clients can branch on them. See InvalidFieldError.Code.
+const (
+	CodeInvalid              = "invalid" // the fallback of unknown errors
+	CodeRequired             = "required"
+	CodeNonzero              = "nonzero"
+	CodeInvalidBool          = "invalid_bool"
+	CodeInvalidInt           = "invalid_int"
+	CodeInvalidUint          = "invalid_uint"
+	CodeInvalidFloat         = "invalid_float"
+	CodeInvalidComplex       = "invalid_complex"
+	CodeInvalidTime          = "invalid_time"
+	CodeOutOfRange           = "out_of_range"
+	CodeTooSmall             = "too_small"
+	CodeTooBig               = "too_big"
+	CodeWrongLength          = "wrong_length"
+	CodeTooShort             = "too_short"
+	CodeTooLong              = "too_long"
+	CodePatternMismatch      = "pattern_mismatch"
+	CodeNotInEnum            = "not_in_enum"
+	CodeInvalidFormat        = "invalid_format"
+	CodeInvalidETag          = "invalid_etag"
+	CodeMalformedCredentials = "malformed_credentials"
+	CodeMalformedBody        = "malformed_body"
+	CodeUnknownField         = "unknown_field"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeUnknownBodyFormat    = "unknown_body_format"
+	CodeUnsupportedType      = "unsupported_type"
+	CodeTypeMismatch         = "type_mismatch"
+)
+
+// CodedError is an error with a stable code and the parameters of its message
+// template, see RegisterMessages. Custom directives, coders and validators can
+// return a CodedError to customize the Code and Params of the resulting
+// InvalidFieldError, e.g.
+//
+//	return core.NewCodedError("weak_password", map[string]any{"min_score": 3}, err)
+type CodedError struct {
+	Code   string
+	Params map[string]any
+	err    error
+}
+
+// NewCodedError creates a CodedError. The message of err is kept as the
+// message of the CodedError.
+func NewCodedError(code string, params map[string]any, err error) *CodedError {
+	return &CodedError{Code: code, Params: params, err: err}
+}
+
+func (e *CodedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.err
+}
+
+// errorCodeOf returns the code and the parameters of the error. The errors of
+// the built-in coders and body formats, and the sentinel errors are recognized,
+// and the others fall back to CodeInvalid.
+func errorCodeOf(err error) (string, map[string]any) {
+	var (
+		codedError    *CodedError
+		numError      *strconv.NumError
+		jsonTypeError *json.UnmarshalTypeError
+		yamlTypeError *yaml.TypeError
+	)
+	switch {
+	case errors.As(err, &codedError):
+		return codedError.Code, codedError.Params
+	case errors.As(err, &numError):
+		return numErrorCode(numError), map[string]any{"value": numError.Num}
+	case errors.Is(err, internal.ErrInvalidTime):
+		return CodeInvalidTime, nil
+	case errors.Is(err, ErrRequestTooLarge):
+		return CodeBodyTooLarge, nil
+	case errors.Is(err, ErrMalformedRequest):
+		return CodeMalformedBody, nil
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return CodeUnsupportedMediaType, nil
+	case errors.Is(err, ErrUnknownBodyFormat):
+		return CodeUnknownBodyFormat, nil
+	case errors.Is(err, ErrMalformedCredentials):
+		return CodeMalformedCredentials, nil
+	case errors.Is(err, ErrInvalidETag):
+		return CodeInvalidETag, nil
+	case errors.Is(err, ErrUnsupportedType):
+		return CodeUnsupportedType, nil
+	case errors.Is(err, ErrTypeMismatch), errors.As(err, &jsonTypeError), errors.As(err, &yamlTypeError):
+		return CodeTypeMismatch, nil
+	}
+	return CodeInvalid, nil
+}
+
+func numErrorCode(err *strconv.NumError) string {
+	if errors.Is(err.Err, strconv.ErrRange) {
+		return CodeOutOfRange
+	}
+	switch err.Func {
+	case "ParseBool":
+		return CodeInvalidBool
+	case "Atoi", "ParseInt":
+		return CodeInvalidInt
+	case "ParseUint":
+		return CodeInvalidUint
+	case "ParseFloat":
+		return CodeInvalidFloat
+	case "ParseComplex":
+		return CodeInvalidComplex
+	}
+	return CodeInvalid
+}
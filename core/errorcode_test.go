@@ -0,0 +1,100 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ErrorCodeInput struct {
+	Bool    bool      `in:"query=bool"`
+	Int     int       `in:"query=int"`
+	Int8    int8      `in:"query=int8"`
+	Uint    uint      `in:"query=uint"`
+	Float   float64   `in:"query=float"`
+	Time    time.Time `in:"query=time"`
+	Name    string    `in:"query=name;required;maxlen=3"`
+	Count   int       `in:"query=count;max=10"`
+	Sort    string    `in:"query=sort;enum=asc|desc"`
+	Email   string    `in:"query=email;format=email"`
+	Code    string    `in:"query=code;pattern=^[a-z]+$"`
+	Tags    []string  `in:"query=tag;len=2"`
+	Comment string    `in:"query=comment;nonzero"`
+}
+
+func TestInvalidFieldError_Code(t *testing.T) {
+	co, err := New(ErrorCodeInput{}, WithCollectAllErrors(true))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/?bool=yes&int=x&int8=300&uint=-1&float=x&time=x"+
+		"&count=11&sort=up&email=x&code=X1&tag=a&comment=", nil)
+	_, err = co.Decode(r)
+	var errs MultiInvalidFieldError
+	assert.ErrorAs(t, err, &errs)
+
+	codes := make(map[string]string)
+	params := make(map[string]map[string]any)
+	for _, e := range errs {
+		codes[e.Field] = e.Code
+		params[e.Field] = e.Params
+	}
+	assert.Equal(t, map[string]string{
+		"Bool":    CodeInvalidBool,
+		"Int":     CodeInvalidInt,
+		"Int8":    CodeOutOfRange,
+		"Uint":    CodeInvalidUint,
+		"Float":   CodeInvalidFloat,
+		"Time":    CodeInvalidTime,
+		"Name":    CodeRequired,
+		"Count":   CodeTooBig,
+		"Sort":    CodeNotInEnum,
+		"Email":   CodeInvalidFormat,
+		"Code":    CodePatternMismatch,
+		"Tags":    CodeWrongLength,
+		"Comment": CodeNonzero,
+	}, codes)
+	assert.Equal(t, map[string]any{"value": "300"}, params["Int8"])
+	assert.Equal(t, map[string]any{"max": int64(10)}, params["Count"])
+	assert.Equal(t, map[string]any{"enum": []string{"asc", "desc"}}, params["Sort"])
+	assert.Equal(t, map[string]any{"len": 2, "length": 1}, params["Tags"])
+}
+
+func TestInvalidFieldError_Code_BodyTypeMismatch(t *testing.T) {
+	type Payload struct {
+		A int `json:"a" yaml:"a" msgpack:"a" cbor:"a"`
+	}
+	type BodyInput struct {
+		Payload Payload `in:"body=auto"`
+	}
+	co, err := New(BodyInput{})
+	assert.NoError(t, err)
+
+	for _, format := range []string{"json", "yaml", "msgpack", "cbor"} {
+		body, err := getBodySerializer(format).Encode(map[string]any{"a": "s"})
+		assert.NoError(t, err)
+		r, _ := http.NewRequest("POST", "/", body)
+		r.Header.Set("Content-Type", getBodyMediaTypes(format)[0])
+		_, err = co.Decode(r)
+		var fe *InvalidFieldError
+		if assert.ErrorAs(t, err, &fe, format) {
+			assert.Equal(t, CodeTypeMismatch, fe.Code, format)
+		}
+	}
+}
+
+func TestNewCodedError(t *testing.T) {
+	err := NewCodedError("weak_password", map[string]any{"min_score": 3}, assert.AnError)
+	assert.Equal(t, assert.AnError.Error(), err.Error())
+	assert.ErrorIs(t, err, assert.AnError)
+
+	fe := NewFieldValidationError("Password", err)
+	assert.Equal(t, "weak_password", fe.Code)
+	assert.Equal(t, map[string]any{"min_score": 3}, fe.Params)
+
+	fe = NewFieldValidationError("Password", errors.New("too weak"))
+	assert.Equal(t, CodeInvalid, fe.Code)
+	assert.Nil(t, fe.Params)
+}
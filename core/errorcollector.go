@@ -0,0 +1,53 @@
+package core
+
+import "github.com/ggicci/owl"
+
+// errorCollector collects the errors of the fields during decoding, see
+// WithCollectAllErrors.
+type errorCollector struct {
+	errors MultiInvalidFieldError
+	failed map[*owl.Resolver]bool
+}
+
+// collectingErrors wraps the decoding function of a directive. When the
+// errors are being collected, the error of the directive is recorded instead
+// of being returned, so that owl continues to resolve the remaining fields.
+// The remaining directives of a failed field, and of its nested fields, are
+// skipped.
+func collectingErrors(decode func(*DirectiveRuntime) error) func(*DirectiveRuntime) error {
+	return func(rtm *DirectiveRuntime) error {
+		collector, _ := rtm.Context.Value(ctxErrorCollector).(*errorCollector)
+		if collector == nil {
+			return decode(rtm)
+		}
+		if collector.hasFailed(rtm.Resolver) {
+			return nil
+		}
+		if err := decode(rtm); err != nil {
+			collector.add(rtm, err)
+		}
+		return nil
+	}
+}
+
+func (c *errorCollector) add(rtm *DirectiveRuntime, err error) {
+	// Build the same error as owl would return when resolving the field.
+	resolveError := &owl.ResolveError{}
+	resolveError.Err = &owl.DirectiveExecutionError{Err: err, Directive: *rtm.Directive}
+	resolveError.Resolver = rtm.Resolver
+	c.errors = append(c.errors, NewInvalidFieldError(resolveError))
+
+	if c.failed == nil {
+		c.failed = make(map[*owl.Resolver]bool)
+	}
+	c.failed[rtm.Resolver] = true
+}
+
+func (c *errorCollector) hasFailed(r *owl.Resolver) bool {
+	for ; r != nil; r = r.Parent {
+		if c.failed[r] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type CollectErrorsInput struct {
+	Page    int    `in:"query=page;min=1"`
+	PerPage int    `in:"query=per_page"`
+	Token   string `in:"header=X-Token;required"`
+	Sort    string `in:"query=sort;enum=asc|desc"`
+}
+
+func TestCore_DecodeTo_CollectAllErrors(t *testing.T) {
+	co, err := New(CollectErrorsInput{}, WithCollectAllErrors(true))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/?page=x&per_page=y&sort=asc", nil)
+	_, err = co.Decode(r)
+	var invalidFields MultiInvalidFieldError
+	assert.ErrorAs(t, err, &invalidFields)
+	if assert.Len(t, invalidFields, 3) {
+		// The "min" directive is skipped after the "query" directive failed.
+		assert.Equal(t, "Page", invalidFields[0].Field)
+		assert.Equal(t, "query", invalidFields[0].Directive)
+		assert.Equal(t, "page", invalidFields[0].Key)
+		assert.Equal(t, []string{"x"}, invalidFields[0].Value)
+		assert.Equal(t, "PerPage", invalidFields[1].Field)
+		assert.Equal(t, "query", invalidFields[1].Directive)
+		assert.Equal(t, "Token", invalidFields[2].Field)
+		assert.Equal(t, "required", invalidFields[2].Directive)
+	}
+
+	r, _ = http.NewRequest("GET", "/?page=1&per_page=2&sort=random", nil)
+	r.Header.Set("X-Token", "abc")
+	_, err = co.Decode(r)
+	assert.ErrorAs(t, err, &invalidFields)
+	if assert.Len(t, invalidFields, 1) {
+		assert.Equal(t, "Sort", invalidFields[0].Field)
+		assert.Equal(t, "enum", invalidFields[0].Directive)
+	}
+
+	r.URL.RawQuery = "page=1&per_page=2&sort=asc"
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &CollectErrorsInput{Page: 1, PerPage: 2, Token: "abc", Sort: "asc"}, got)
+
+	// Stop at the first error by default.
+	co, err = New(CollectErrorsInput{})
+	assert.NoError(t, err)
+	r, _ = http.NewRequest("GET", "/?page=x&per_page=y", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Page", invalidField.Field)
+	assert.NotErrorAs(t, err, &invalidFields)
+}
+
+func TestCore_DecodeTo_CollectAllErrors_Nested(t *testing.T) {
+	type Pager struct {
+		Page    int `in:"query=page"`
+		PerPage int `in:"query=per_page"`
+	}
+	type Input struct {
+		Pager Pager
+		Sort  string `in:"query=sort;required"`
+	}
+
+	co, err := New(Input{}, WithCollectAllErrors(true))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/?page=x&per_page=y", nil)
+	_, err = co.Decode(r)
+	var invalidFields MultiInvalidFieldError
+	assert.ErrorAs(t, err, &invalidFields)
+	if assert.Len(t, invalidFields, 3) {
+		assert.Equal(t, "Page", invalidFields[0].Field)
+		assert.Equal(t, "PerPage", invalidFields[1].Field)
+		assert.Equal(t, "Sort", invalidFields[2].Field)
+	}
+}
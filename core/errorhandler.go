@@ -19,15 +19,72 @@ func RegisterErrorHandler(handler ErrorHandler) {
 }
 
 func defaultErrorHandler(rw http.ResponseWriter, r *http.Request, err error) {
+	status := ErrorStatusCode(r, err)
+	if status >= http.StatusInternalServerError {
+		http.Error(rw, http.StatusText(status), status) // no details of server errors
+		return
+	}
+
+	var invalidFieldErrors MultiInvalidFieldError
+	if errors.As(err, &invalidFieldErrors) {
+		rw.Header().Add("Content-Type", "application/json")
+		rw.WriteHeader(status)
+		json.NewEncoder(rw).Encode(localizeFieldErrors(r, invalidFieldErrors))
+		return
+	}
+
 	var invalidFieldError *InvalidFieldError
 	if errors.As(err, &invalidFieldError) {
 		rw.Header().Add("Content-Type", "application/json")
-		rw.WriteHeader(http.StatusUnprocessableEntity) // status: 422
-		json.NewEncoder(rw).Encode(invalidFieldError)
+		rw.WriteHeader(status)
+		json.NewEncoder(rw).Encode(localizeFieldErrors(r, MultiInvalidFieldError{invalidFieldError})[0])
 		return
 	}
 
-	http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError) // status: 500
+	http.Error(rw, http.StatusText(status), status)
+}
+
+// ErrorStatusMapper maps an error returned by decoding to an HTTP status code.
+type ErrorStatusMapper = func(err error) int
+
+// DefaultErrorStatusMapper is the default ErrorStatusMapper, which maps the
+// errors to the following status codes:
+//
+//   - 413 Request Entity Too Large: ErrRequestTooLarge;
+//   - 415 Unsupported Media Type: ErrUnsupportedMediaType;
+//   - 400 Bad Request: ErrMalformedRequest, ErrFailedToParseRequestForm;
+//   - 422 Unprocessable Entity: other InvalidFieldError and MultiInvalidFieldError;
+//   - 500 Internal Server Error: ErrUnknownBodyFormat, i.e. a misconfigured
+//     body format, and the others.
+func DefaultErrorStatusMapper(err error) int {
+	var (
+		invalidFieldError  *InvalidFieldError
+		invalidFieldErrors MultiInvalidFieldError
+	)
+	switch {
+	case errors.Is(err, ErrRequestTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrMalformedRequest), errors.Is(err, ErrFailedToParseRequestForm):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnknownBodyFormat):
+		return http.StatusInternalServerError
+	case errors.As(err, &invalidFieldErrors), errors.As(err, &invalidFieldError):
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorStatusCode returns the HTTP status code of the error returned by
+// decoding the request r. It's resolved by the ErrorStatusMapper set by
+// WithErrorStatusMapper, or DefaultErrorStatusMapper. Custom error handlers can
+// use it to respond in line with the default error handler.
+func ErrorStatusCode(r *http.Request, err error) int {
+	if mapper, ok := r.Context().Value(ctxErrorStatusMapper).(ErrorStatusMapper); ok {
+		return mapper(err)
+	}
+	return DefaultErrorStatusMapper(err)
 }
 
 // ErrorHandler is the type of custom error handler. The error handler is used
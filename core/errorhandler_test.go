@@ -2,9 +2,11 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,8 +40,80 @@ func TestDefaultErrorHandler(t *testing.T) {
 	defaultErrorHandler(rw, r, &InvalidFieldError{err: assert.AnError, ErrorMessage: assert.AnError.Error()})
 	assert.Equal(t, 422, rw.Code)
 
+	// When met MultiInvalidFieldError, it should render the full list.
+	rw = httptest.NewRecorder()
+	defaultErrorHandler(rw, r, MultiInvalidFieldError{
+		{err: assert.AnError, Field: "A", ErrorMessage: "a"},
+		{err: assert.AnError, Field: "B", ErrorMessage: "b"},
+	})
+	assert.Equal(t, 422, rw.Code)
+	assert.JSONEq(t, `[
+		{"field":"A","directive":"","key":"","value":null,"error":"a"},
+		{"field":"B","directive":"","key":"","value":null,"error":"b"}
+	]`, rw.Body.String())
+
 	// When met other errors, it should return 500.
 	rw = httptest.NewRecorder()
 	defaultErrorHandler(rw, r, assert.AnError)
 	assert.Equal(t, 500, rw.Code)
 }
+
+func TestDefaultErrorStatusMapper(t *testing.T) {
+	for _, c := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: %w", ErrFailedToParseRequestForm, assert.AnError), 400},
+		{&InvalidFieldError{err: fmt.Errorf("%w: %w", ErrMalformedRequest, io.EOF)}, 400},
+		{fmt.Errorf("%w: %w", ErrFailedToParseRequestForm, classifyRequestError(&http.MaxBytesError{Limit: 8})), 413},
+		{&InvalidFieldError{err: fmt.Errorf("%w: %q", ErrUnsupportedMediaType, "text/plain")}, 415},
+		{ErrUnsupportedMediaType, 415},
+		{&InvalidFieldError{err: fmt.Errorf("%w: %q", ErrUnknownBodyFormat, "yaml")}, 500},
+		{&InvalidFieldError{err: assert.AnError}, 422},
+		{MultiInvalidFieldError{{err: assert.AnError}}, 422},
+		{assert.AnError, 500},
+	} {
+		assert.Equal(t, c.status, DefaultErrorStatusMapper(c.err), c.err)
+	}
+}
+
+func TestDefaultErrorHandler_StatusCodes(t *testing.T) {
+	type BodyInput struct {
+		Payload *BodyPayload `in:"body=json"`
+	}
+	co, err := New(BodyInput{}, WithErrorHandler(defaultErrorHandler))
+	assert.NoError(t, err)
+
+	decode := func(r *http.Request) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		r.Header.Set("Content-Type", "application/json")
+		_, err := co.Decode(r)
+		assert.Error(t, err)
+		co.GetErrorHandler()(rw, r, err)
+		return rw
+	}
+
+	// Malformed JSON body.
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name": "Elia"`))
+	rw := decode(r)
+	assert.Equal(t, 400, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"field":"Payload"`)
+
+	// Oversized body.
+	r, _ = http.NewRequest("POST", "/", nil)
+	r.Body = http.MaxBytesReader(nil, io.NopCloser(strings.NewReader(sampleBodyPayloadInJSONText)), 16)
+	rw = decode(r)
+	assert.Equal(t, 413, rw.Code)
+
+	// Custom status mapper.
+	co, err = New(BodyInput{}, WithErrorHandler(defaultErrorHandler), WithErrorStatusMapper(func(err error) int {
+		if errors.Is(err, ErrMalformedRequest) {
+			return http.StatusTeapot
+		}
+		return DefaultErrorStatusMapper(err)
+	}))
+	assert.NoError(t, err)
+	r, _ = http.NewRequest("POST", "/", strings.NewReader(`{`))
+	rw = decode(r)
+	assert.Equal(t, http.StatusTeapot, rw.Code)
+}
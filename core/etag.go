@@ -0,0 +1,207 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidETag = errors.New("invalid entity tag")
+
+// ETag is an entity tag, e.g. "xyzzy" or W/"xyzzy". See RFC 9110, Section
+// 8.8.3. It can be used as the field of the ETag or If-Range header, e.g.
+//
+//	type Input struct {
+//		IfRange *core.ETag `in:"header=If-Range"`
+//	}
+type ETag struct {
+	Tag  string // the opaque tag without quotes
+	Weak bool
+}
+
+// ETagList is the content of an If-Match or If-None-Match header, which is
+// either "*" or a list of entity tags. See RFC 9110, Section 13.1. e.g.
+//
+//	type UpdateArticleInput struct {
+//		IfMatch core.ETagList `in:"header=If-Match"`
+//	}
+//
+// Multiple field lines of the header are combined before parsing. An empty
+// ETagList is not encoded, i.e. no header will be set.
+type ETagList struct {
+	Any  bool // "*", matches any current representation
+	Tags []ETag
+}
+
+// Equal reports whether the two entity tags are equivalent. The weak
+// comparison only compares the opaque tags, while the strong comparison also
+// requires both of them to be strong. See RFC 9110, Section 8.8.3.2.
+func (e ETag) Equal(other ETag, weak bool) bool {
+	if !weak && (e.Weak || other.Weak) {
+		return false
+	}
+	return e.Tag == other.Tag
+}
+
+func (e ETag) ToString() (string, error) {
+	if !isValidETagOpaqueTag(e.Tag) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidETag, e.Tag)
+	}
+	s := `"` + e.Tag + `"`
+	if e.Weak {
+		s = "W/" + s
+	}
+	return s, nil
+}
+
+func (e *ETag) FromString(s string) error {
+	p := &etagParser{s: strings.TrimSpace(s)}
+	etag, err := p.parseETag()
+	if err != nil {
+		return err
+	}
+	if !p.eof() {
+		return fmt.Errorf("%w: %q", ErrInvalidETag, s)
+	}
+	*e = etag
+	return nil
+}
+
+// Matches reports whether the etag of the current representation matches the
+// list. Use weak comparison for If-None-Match and strong comparison for
+// If-Match. "*" matches any etag.
+func (l ETagList) Matches(etag ETag, weak bool) bool {
+	if l.Any {
+		return true
+	}
+	for _, tag := range l.Tags {
+		if tag.Equal(etag, weak) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the list is neither "*" nor contains any entity tag.
+func (l ETagList) IsEmpty() bool {
+	return !l.Any && len(l.Tags) == 0
+}
+
+func (l ETagList) ToString() (string, error) {
+	if l.Any {
+		return "*", nil
+	}
+	tags := make([]string, len(l.Tags))
+	for i, tag := range l.Tags {
+		s, err := tag.ToString()
+		if err != nil {
+			return "", err
+		}
+		tags[i] = s
+	}
+	return strings.Join(tags, ", "), nil
+}
+
+func (l *ETagList) FromString(s string) error {
+	return l.FromStringSlice([]string{s})
+}
+
+func (l ETagList) ToStringSlice() ([]string, error) {
+	if l.IsEmpty() {
+		return []string{}, nil // an empty list is not encoded
+	}
+	s, err := l.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func (l *ETagList) FromStringSlice(values []string) error {
+	var list ETagList
+	for _, value := range values {
+		p := &etagParser{s: value}
+		for {
+			p.discardListSeparators()
+			if p.eof() {
+				break
+			}
+			if p.peek() == '*' {
+				p.pos++
+				list.Any = true
+			} else {
+				etag, err := p.parseETag()
+				if err != nil {
+					return err
+				}
+				list.Tags = append(list.Tags, etag)
+			}
+			p.discardOWS()
+			if !p.eof() && p.peek() != ',' {
+				return fmt.Errorf("%w: unexpected character %q in %q", ErrInvalidETag, p.peek(), value)
+			}
+		}
+	}
+	if list.Any && len(list.Tags) > 0 {
+		return fmt.Errorf("%w: \"*\" cannot be combined with other entity tags", ErrInvalidETag)
+	}
+	*l = list
+	return nil
+}
+
+// etagParser parses entity tags. Commas are allowed in the opaque tags, so
+// the lists cannot be simply split by commas.
+//
+//	entity-tag = [ "W/" ] DQUOTE *etagc DQUOTE
+type etagParser struct {
+	s   string
+	pos int
+}
+
+func (p *etagParser) eof() bool { return p.pos >= len(p.s) }
+
+func (p *etagParser) peek() byte { return p.s[p.pos] }
+
+func (p *etagParser) discardOWS() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+func (p *etagParser) discardListSeparators() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == ',') {
+		p.pos++
+	}
+}
+
+func (p *etagParser) parseETag() (ETag, error) {
+	var etag ETag
+	if strings.HasPrefix(p.s[p.pos:], "W/") {
+		etag.Weak = true
+		p.pos += 2
+	}
+	if p.eof() || p.peek() != '"' {
+		return ETag{}, fmt.Errorf("%w: %q", ErrInvalidETag, p.s)
+	}
+	end := strings.IndexByte(p.s[p.pos+1:], '"')
+	if end == -1 {
+		return ETag{}, fmt.Errorf("%w: %q", ErrInvalidETag, p.s)
+	}
+	etag.Tag = p.s[p.pos+1 : p.pos+1+end]
+	if !isValidETagOpaqueTag(etag.Tag) {
+		return ETag{}, fmt.Errorf("%w: %q", ErrInvalidETag, p.s)
+	}
+	p.pos += end + 2
+	return etag, nil
+}
+
+// isValidETagOpaqueTag reports whether all the characters of the tag are
+// etagc, i.e. %x21 / %x23-7E / obs-text.
+func isValidETagOpaqueTag(tag string) bool {
+	for i := 0; i < len(tag); i++ {
+		if c := tag[i]; c < 0x21 || c == '"' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
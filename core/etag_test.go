@@ -0,0 +1,116 @@
+package core
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	var etag ETag
+	assert.NoError(t, etag.FromString(`W/"xyzzy"`))
+	assert.Equal(t, ETag{Tag: "xyzzy", Weak: true}, etag)
+	s, err := etag.ToString()
+	assert.NoError(t, err)
+	assert.Equal(t, `W/"xyzzy"`, s)
+
+	assert.NoError(t, etag.FromString(`""`))
+	assert.Equal(t, ETag{}, etag)
+
+	for _, invalid := range []string{`xyzzy`, `"xyzzy`, `w/"xyzzy"`, `"a b"`, `"a", "b"`} {
+		assert.ErrorIs(t, etag.FromString(invalid), ErrInvalidETag, invalid)
+	}
+	_, err = ETag{Tag: `a"b`}.ToString()
+	assert.ErrorIs(t, err, ErrInvalidETag)
+}
+
+func TestETag_Equal(t *testing.T) {
+	// RFC 9110, Section 8.8.3.2.
+	for _, c := range []struct {
+		a, b         ETag
+		strong, weak bool
+	}{
+		{ETag{"1", true}, ETag{"1", true}, false, true},
+		{ETag{"1", true}, ETag{"2", true}, false, false},
+		{ETag{"1", true}, ETag{"1", false}, false, true},
+		{ETag{"1", false}, ETag{"1", false}, true, true},
+	} {
+		assert.Equal(t, c.strong, c.a.Equal(c.b, false))
+		assert.Equal(t, c.weak, c.a.Equal(c.b, true))
+	}
+}
+
+func TestETagList(t *testing.T) {
+	var list ETagList
+	assert.NoError(t, list.FromStringSlice([]string{`"xyzzy", W/"r2d2,xxxx"`, `"c3piozzzz",`}))
+	assert.Equal(t, ETagList{Tags: []ETag{
+		{Tag: "xyzzy"}, {Tag: "r2d2,xxxx", Weak: true}, {Tag: "c3piozzzz"},
+	}}, list)
+	assert.True(t, list.Matches(ETag{Tag: "xyzzy"}, false))
+	assert.False(t, list.Matches(ETag{Tag: "r2d2,xxxx"}, false))
+	assert.True(t, list.Matches(ETag{Tag: "r2d2,xxxx"}, true))
+	assert.False(t, list.Matches(ETag{Tag: "other"}, true))
+
+	values, err := list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`"xyzzy", W/"r2d2,xxxx", "c3piozzzz"`}, values)
+
+	assert.NoError(t, list.FromString(" * "))
+	assert.Equal(t, ETagList{Any: true}, list)
+	assert.True(t, list.Matches(ETag{Tag: "anything"}, false))
+	values, err = list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"*"}, values)
+
+	assert.NoError(t, list.FromString(""))
+	assert.True(t, list.IsEmpty())
+	assert.False(t, list.Matches(ETag{Tag: "xyzzy"}, true))
+	values, err = list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	assert.ErrorIs(t, list.FromString(`*, "xyzzy"`), ErrInvalidETag)
+	assert.ErrorIs(t, list.FromString(`"a" "b"`), ErrInvalidETag)
+	assert.ErrorIs(t, list.FromString(`xyzzy`), ErrInvalidETag)
+}
+
+func TestDirectiveHeader_ETag(t *testing.T) {
+	type Input struct {
+		IfMatch     ETagList `in:"header=If-Match"`
+		IfNoneMatch ETagList `in:"header=If-None-Match"`
+		IfRange     *ETag    `in:"header=If-Range;omitempty"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/articles/1", nil)
+	r.Header.Set("If-Match", `"v1", "v2"`)
+	r.Header.Set("If-None-Match", "*")
+	r.Header.Set("If-Range", `W/"v0"`)
+	expected := &Input{
+		IfMatch:     ETagList{Tags: []ETag{{Tag: "v1"}, {Tag: "v2"}}},
+		IfNoneMatch: ETagList{Any: true},
+		IfRange:     &ETag{Tag: "v0", Weak: true},
+	}
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	req, err := co.NewRequest("GET", "/articles/1", expected)
+	assert.NoError(t, err)
+	assert.Equal(t, `"v1", "v2"`, req.Header.Get("If-Match"))
+	assert.Equal(t, "*", req.Header.Get("If-None-Match"))
+	assert.Equal(t, `W/"v0"`, req.Header.Get("If-Range"))
+
+	req, err = co.NewRequest("GET", "/articles/1", &Input{})
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Values("If-Match"))
+	assert.Empty(t, req.Header.Values("If-None-Match"))
+	assert.Empty(t, req.Header.Values("If-Range"))
+
+	r.Header.Set("If-Match", "v1")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrInvalidETag)
+}
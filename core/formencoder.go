@@ -20,6 +20,17 @@ func (e *FormEncoder) Execute(rtm *DirectiveRuntime) error {
 	}
 
 	key := rtm.Directive.Argv[0]
+	if rtm.isDeepObjectStyle() {
+		if err := encodeDeepObject(rtm.Value, key, e.Setter); err != nil {
+			return err
+		}
+		rtm.MarkFieldSet(true)
+		return nil
+	}
+	if prefix, ok := wildcardPrefix(key); ok {
+		return e.encodeWildcard(rtm, prefix)
+	}
+
 	valueType := rtm.Value.Type()
 	// When baseType is a file type, we treat it as a file upload.
 	if isFileType(valueType) {
@@ -55,7 +66,8 @@ func (e *FormEncoder) Execute(rtm *DirectiveRuntime) error {
 	if values, err := encoder.ToStringSlice(); err != nil {
 		return err
 	} else {
-		e.Setter(key, values)
+		values = rtm.getFieldTransforms().apply(values)
+		e.Setter(key, rtm.getFieldStyle().joinValues(values))
 		rtm.MarkFieldSet(true)
 		return nil
 	}
@@ -30,6 +30,13 @@ func (e *FormExtractor) extract(key string) error {
 		return nil // skip when already extracted by former directives
 	}
 
+	if e.Runtime.isDeepObjectStyle() {
+		return e.extractDeepObject(key)
+	}
+	if prefix, ok := wildcardPrefix(key); ok {
+		return e.extractWildcard(prefix)
+	}
+
 	values := e.Form.Value[key]
 	files := e.Form.File[key]
 
@@ -59,6 +66,8 @@ func (e *FormExtractor) extract(key string) error {
 			return nil // skip when no value given
 		}
 		sourceValue = values
+		values = e.Runtime.getFieldStyle().splitValues(values)
+		values = e.Runtime.getFieldTransforms().apply(values)
 
 		var adapt AnyStringableAdaptor
 		decoderInfo := e.Runtime.GetCustomCoder() // custom decoder, specified by "decoder" directive
@@ -78,3 +87,16 @@ func (e *FormExtractor) extract(key string) error {
 	e.Runtime.MarkFieldSet(true)
 	return nil
 }
+
+// extractDeepObject extracts the values whose keys are in bracket notation,
+// e.g. filter[status]=open, see StyleDeepObject.
+func (e *FormExtractor) extractDeepObject(key string) error {
+	found, err := decodeDeepObject(e.Runtime.Value.Elem(), key, e.Form.Value)
+	if err != nil {
+		return err
+	}
+	if found {
+		e.Runtime.MarkFieldSet(true)
+	}
+	return nil
+}
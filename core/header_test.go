@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/ggicci/httpin/core/sfv"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -73,3 +74,44 @@ func TestDirectiveHeader_NewRequest(t *testing.T) {
 		assert.False(t, ok)
 	})
 }
+
+func TestDirectiveHeader_StructuredFieldValues(t *testing.T) {
+	type Input struct {
+		Priority    sfv.Dictionary `in:"header=Priority"`
+		CacheStatus sfv.List       `in:"header=Cache-Status"`
+		Viewport    *sfv.Item      `in:"header=Sec-CH-Viewport-Width"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("Priority", "u=1, i")
+	r.Header.Add("Cache-Status", "ExampleCache; hit")
+	r.Header.Add("Cache-Status", "OriginCache; fwd=uri-miss")
+	r.Header.Set("Sec-CH-Viewport-Width", "1280")
+	expected := &Input{
+		Priority: sfv.Dictionary{
+			{Key: "u", Value: sfv.Item{Value: int64(1)}},
+			{Key: "i", Value: sfv.Item{Value: true}},
+		},
+		CacheStatus: sfv.List{
+			sfv.Item{Value: sfv.Token("ExampleCache"), Params: sfv.Params{{Key: "hit", Value: true}}},
+			sfv.Item{Value: sfv.Token("OriginCache"), Params: sfv.Params{{Key: "fwd", Value: sfv.Token("uri-miss")}}},
+		},
+		Viewport: &sfv.Item{Value: int64(1280)},
+	}
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	req, err := co.NewRequest("GET", "/", expected)
+	assert.NoError(t, err)
+	assert.Equal(t, "u=1, i", req.Header.Get("Priority"))
+	assert.Equal(t, "ExampleCache;hit, OriginCache;fwd=uri-miss", req.Header.Get("Cache-Status"))
+	assert.Equal(t, "1280", req.Header.Get("Sec-CH-Viewport-Width"))
+
+	r.Header.Set("Priority", "u=1,")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, sfv.ErrInvalidSyntax)
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/ggicci/httpin/internal"
+	"github.com/ggicci/owl"
+)
+
+// httpDateAdaptor is the coder of the time.Time fields of the HTTP-date
+// headers. It's not registered as a named coder, so that it doesn't take any
+// name from the users, see applyHTTPDateCoder.
+var httpDateAdaptor = &NamedAnyStringableAdaptor{
+	Name:     "httpdate",
+	BaseType: timeType,
+	Adapt: internal.NewAnyStringableAdaptor[time.Time](func(t *time.Time) (Stringable, error) {
+		return (*httpDate)(t), nil
+	}),
+}
+
+// httpDateHeaders are the headers whose values are HTTP-dates. The HTTP-date
+// coder is applied to the time.Time fields of these headers automatically.
+var httpDateHeaders = map[string]bool{
+	"Date":                true,
+	"Expires":             true,
+	"Last-Modified":       true,
+	"If-Modified-Since":   true,
+	"If-Unmodified-Since": true,
+}
+
+// httpDate is a time.Time in the format of HTTP-date, e.g.
+// "Sun, 06 Nov 1994 08:49:37 GMT". See RFC 9110, Section 5.6.7. The obsolete
+// formats (RFC 850 and ANSI C's asctime) are also accepted when decoding.
+type httpDate time.Time
+
+func (d httpDate) ToString() (string, error) {
+	return time.Time(d).UTC().Format(http.TimeFormat), nil
+}
+
+func (d *httpDate) FromString(s string) error {
+	t, err := http.ParseTime(s)
+	if err != nil {
+		return NewCodedError(CodeInvalidTime, nil, fmt.Errorf("invalid HTTP-date %q", s))
+	}
+	*d = httpDate(t.UTC())
+	return nil
+}
+
+// applyHTTPDateCoder uses the HTTP-date coder for the time.Time fields of
+// the HTTP-date headers, e.g. If-Modified-Since. An explicit "coder" directive
+// takes precedence.
+func applyHTTPDateCoder(r *owl.Resolver) error {
+	d := r.GetDirective("header")
+	if d == nil || r.Context.Value(CtxCustomCoder) != nil {
+		return nil
+	}
+	baseType, _ := BaseTypeOf(r.Type)
+	if baseType.Kind() == reflect.Pointer {
+		baseType = baseType.Elem()
+	}
+	if baseType != timeType {
+		return nil
+	}
+	for _, key := range d.Argv {
+		if httpDateHeaders[http.CanonicalHeaderKey(key)] {
+			r.Context = context.WithValue(r.Context, CtxCustomCoder, httpDateAdaptor)
+			return nil
+		}
+	}
+	return nil
+}
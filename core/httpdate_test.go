@@ -0,0 +1,98 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectiveHeader_HTTPDate(t *testing.T) {
+	type Input struct {
+		IfModifiedSince   *time.Time             `in:"header=If-Modified-Since"`
+		IfUnmodifiedSince patch.Field[time.Time] `in:"header=if-unmodified-since"`
+		Since             time.Time              `in:"header=X-Since"` // not an HTTP-date header
+		Date              time.Time              `in:"header=Date;coder=custom_time"`
+	}
+	RegisterNamedCoder[time.Time]("custom_time", func(t *time.Time) (Stringable, error) {
+		return (*httpDate)(t), nil
+	})
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	ts := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+	for _, value := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",  // IMF-fixdate
+		"Sunday, 06-Nov-94 08:49:37 GMT", // obsolete RFC 850 format
+		"Sun Nov  6 08:49:37 1994",       // ANSI C's asctime() format
+	} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("If-Modified-Since", value)
+		r.Header.Set("If-Unmodified-Since", value)
+		r.Header.Set("X-Since", "1994-11-06T08:49:37Z")
+		got, err := co.Decode(r)
+		assert.NoError(t, err, value)
+		assert.Equal(t, &Input{
+			IfModifiedSince:   &ts,
+			IfUnmodifiedSince: patch.Field[time.Time]{Value: ts, Valid: true},
+			Since:             ts,
+		}, got)
+	}
+
+	req, err := co.NewRequest("GET", "/", &Input{
+		IfModifiedSince:   &ts,
+		IfUnmodifiedSince: patch.Field[time.Time]{Value: ts.In(time.FixedZone("UTC+8", 8*3600)), Valid: true},
+		Since:             ts,
+		Date:              ts,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", req.Header.Get("If-Modified-Since"))
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", req.Header.Get("If-Unmodified-Since"))
+	assert.Equal(t, "1994-11-06T08:49:37Z", req.Header.Get("X-Since"))
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", req.Header.Get("Date"))
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("If-Modified-Since", "1994-11-06T08:49:37Z")
+	_, err = co.Decode(r)
+	assert.ErrorContains(t, err, "invalid HTTP-date")
+}
+
+func TestDirectiveHeader_HTTPDate_NameNotTaken(t *testing.T) {
+	type Input struct {
+		IfModifiedSince time.Time `in:"header=If-Modified-Since"`
+		Since           string    `in:"header=X-Since;coder=httpdate"`
+	}
+
+	// The name "httpdate" is free for the users.
+	RegisterNamedCoder[string]("httpdate", func(s *string) (Stringable, error) {
+		return (*upperString)(s), nil
+	})
+	defer delete(namedStringableAdaptors, "httpdate")
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.Header.Set("If-Modified-Since", "Sun, 06 Nov 1994 08:49:37 GMT")
+	r.Header.Set("X-Since", "yesterday")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Input{
+		IfModifiedSince: time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC),
+		Since:           "YESTERDAY",
+	}, got)
+}
+
+type upperString string
+
+func (s upperString) ToString() (string, error) {
+	return string(s), nil
+}
+
+func (s *upperString) FromString(value string) error {
+	*s = upperString(strings.ToUpper(value))
+	return nil
+}
@@ -0,0 +1,291 @@
+// directive: "jsonptr"
+// https://ggicci.github.io/httpin/directives/jsonptr
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ggicci/owl"
+)
+
+// DirectiveJSONPointer implements the "jsonptr" executor who extracts values
+// from a JSON request body by JSON Pointers (RFC 6901). The body is read and
+// parsed only once per request, no matter how many fields refer to it. e.g.
+//
+//	type UpdateUserInput struct {
+//		ID    int    `in:"jsonptr=/user/id;required"`
+//		Email string `in:"jsonptr=/user/contact/email"`
+//		Token string `in:"header=x-api-token"`
+//	}
+//
+// Scalar values (strings, numbers, booleans) and arrays of scalar values are
+// decoded by the coders, the same as the values of the "query" directive.
+// Objects and arrays of objects are decoded by encoding/json. Null values are
+// treated as absent.
+//
+// NOTE: the pointers cannot contain commas, which are used to separate the
+// arguments of a directive.
+type DirectiveJSONPointer struct{}
+
+func (*DirectiveJSONPointer) Decode(rtm *DirectiveRuntime) error {
+	if rtm.IsFieldSet() {
+		return nil // skip when already extracted by former directives
+	}
+
+	document, ok, err := rtm.getJSONDocument()
+	if err != nil {
+		return &fieldError{rtm.Directive.Argv[0], nil, fmt.Errorf("invalid JSON body: %w", err)}
+	}
+	if !ok {
+		return nil // empty body
+	}
+
+	for _, pointer := range rtm.Directive.Argv {
+		tokens, err := parseJSONPointer(pointer)
+		if err != nil {
+			return err
+		}
+		value, found := lookupJSONPointer(document, tokens)
+		if !found || value == nil {
+			continue
+		}
+		return decodeJSONValue(rtm, pointer, value)
+	}
+	return nil
+}
+
+// Encode sets the value of the field in a JSON document at the location
+// specified by the JSON pointer. The document will be used as the JSON body of
+// the HTTP request.
+func (*DirectiveJSONPointer) Encode(rtm *DirectiveRuntime) error {
+	if rtm.Value.IsZero() {
+		if rtm.Resolver.GetDirective("omitempty") != nil {
+			return nil
+		}
+	}
+	if rtm.IsFieldSet() {
+		return nil // skip when already encoded by former directives
+	}
+	if IsPatchField(rtm.Value.Type()) && !rtm.Value.FieldByName("Valid").Bool() {
+		return nil // skip when the patch field is not set
+	}
+
+	var value any = rtm.Value.Interface()
+	if coder := rtm.GetCustomCoder(); coder != nil {
+		encoder, err := NewStringSlicable(rtm.Value, coder.Adapt)
+		if err != nil {
+			return err
+		}
+		values, err := encoder.ToStringSlice()
+		if err != nil {
+			return err
+		}
+		if isSliceType(rtm.Value.Type()) {
+			value = values
+		} else if len(values) > 0 {
+			value = values[0]
+		}
+	}
+
+	if err := rtm.GetRequestBuilder().SetJSONPointer(rtm.Directive.Argv[0], value); err != nil {
+		return err
+	}
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+// getJSONDocument parses the request body as a JSON document once and caches
+// it. Returns false if the request body is empty.
+func (rtm *DirectiveRuntime) getJSONDocument() (any, bool, error) {
+	cache := rtm.getRequestCache()
+	if cache == nil {
+		cache = &requestCache{}
+	}
+	if !cache.jsonParsed {
+		cache.jsonParsed = true
+		var body []byte
+		body, cache.jsonErr = rtm.readRequestBody()
+		if cache.jsonErr == nil && len(bytes.TrimSpace(body)) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber() // keep the literal of the numbers
+			cache.jsonErr = classifyRequestError(decoder.Decode(&cache.jsonDocument))
+		}
+	}
+	return cache.jsonDocument, cache.jsonDocument != nil, cache.jsonErr
+}
+
+func decodeJSONValue(rtm *DirectiveRuntime, pointer string, value any) error {
+	fieldType := rtm.Value.Type().Elem()
+	if fieldType.Kind() == reflect.Interface {
+		if err := rtm.SetValue(value); err != nil {
+			return &fieldError{pointer, value, err}
+		}
+		rtm.MarkFieldSet(true)
+		return nil
+	}
+
+	if values, ok := jsonScalarStrings(value); ok {
+		extractor := &FormExtractor{
+			Runtime: rtm,
+			Form: multipart.Form{
+				Value: map[string][]string{pointer: values},
+			},
+		}
+		return extractor.Extract(pointer)
+	}
+
+	// Objects, decoded by encoding/json.
+	raw, err := json.Marshal(value)
+	if err == nil {
+		err = json.Unmarshal(raw, rtm.Value.Interface())
+	}
+	if err != nil {
+		return &fieldError{pointer, value, err}
+	}
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+// jsonScalarStrings converts a scalar value or an array of scalar values to
+// strings. Returns false if the value is an object or an array containing
+// non-scalar values.
+func jsonScalarStrings(value any) ([]string, bool) {
+	if array, ok := value.([]any); ok {
+		values := make([]string, 0, len(array))
+		for _, elem := range array {
+			s, ok := jsonScalarString(elem)
+			if !ok {
+				return nil, false
+			}
+			values = append(values, s)
+		}
+		return values, true
+	}
+	if s, ok := jsonScalarString(value); ok {
+		return []string{s}, true
+	}
+	return nil, false
+}
+
+func jsonScalarString(value any) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// parseJSONPointer parses a JSON Pointer (RFC 6901) into reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil // the whole document
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with \"/\"", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: bad escape sequence", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// lookupJSONPointer returns the value in the document referenced by the tokens.
+func lookupJSONPointer(document any, tokens []string) (any, bool) {
+	current := document
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []any:
+			index, ok := parseJSONArrayIndex(token)
+			if !ok || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// parseJSONArrayIndex parses an array index, leading zeros are not allowed.
+func parseJSONArrayIndex(token string) (int, bool) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, false
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	index, err := strconv.Atoi(token)
+	return index, err == nil
+}
+
+// setJSONPointer sets the value in the document at the location referenced by
+// the tokens, the missing objects along the path are created. Returns the
+// updated document.
+func setJSONPointer(document any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	var object map[string]any
+	switch node := document.(type) {
+	case nil:
+		object = make(map[string]any)
+	case map[string]any:
+		object = node
+	default:
+		return nil, errors.New("cannot set a member of a non-object value")
+	}
+	child, err := setJSONPointer(object[tokens[0]], tokens[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	object[tokens[0]] = child
+	return object, nil
+}
+
+// validateJSONPointerDirective ensures that the JSON pointers of the "jsonptr"
+// directive are valid.
+func validateJSONPointerDirective(r *owl.Resolver) error {
+	d := r.GetDirective("jsonptr")
+	if d == nil {
+		return nil
+	}
+	if len(d.Argv) == 0 {
+		return errors.New("directive jsonptr: missing JSON pointer")
+	}
+	if isFileType(r.Type) {
+		return errors.New("directive jsonptr: cannot be used on a file type field")
+	}
+	for _, pointer := range d.Argv {
+		if _, err := parseJSONPointer(pointer); err != nil {
+			return fmt.Errorf("directive jsonptr: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+var sampleJSONPointerBody = `{
+	"user": {
+		"id": 42,
+		"name": "ggicci",
+		"active": true,
+		"score": 9.5,
+		"tags": ["a", "b"],
+		"birthday": "1991-11-10",
+		"address": {"city": "Toronto", "country": "Canada"},
+		"nickname": null,
+		"a/b": "slash",
+		"m~n": "tilde"
+	},
+	"items": [{"id": 1}, {"id": 2}]
+}`
+
+type JSONPointerInput struct {
+	ID       int                  `in:"jsonptr=/user/id;required"`
+	Name     string               `in:"jsonptr=/user/name"`
+	Active   bool                 `in:"jsonptr=/user/active"`
+	Score    patch.Field[float64] `in:"jsonptr=/user/score"`
+	Tags     []string             `in:"jsonptr=/user/tags"`
+	Birthday time.Time            `in:"jsonptr=/user/birthday"`
+	Address  *Address             `in:"jsonptr=/user/address"`
+	Nickname string               `in:"jsonptr=/user/nickname;default=anonymous"`
+	Slash    string               `in:"jsonptr=/user/a~1b"`
+	Tilde    string               `in:"jsonptr=/user/m~0n"`
+	SecondID int                  `in:"jsonptr=/items/1/id"`
+	Missing  patch.Field[int]     `in:"jsonptr=/items/2/id"`
+	Raw      any                  `in:"jsonptr=/user/address/city"`
+	Token    string               `in:"header=x-api-token"`
+}
+
+type Address struct {
+	City    string `json:"city"`
+	Country string `json:"country"`
+}
+
+func TestDirectiveJSONPointer_Decode(t *testing.T) {
+	co, err := New(JSONPointerInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(sampleJSONPointerBody))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Api-Token", "secret")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &JSONPointerInput{
+		ID:       42,
+		Name:     "ggicci",
+		Active:   true,
+		Score:    patch.Field[float64]{Value: 9.5, Valid: true},
+		Tags:     []string{"a", "b"},
+		Birthday: time.Date(1991, 11, 10, 0, 0, 0, 0, time.UTC),
+		Address:  &Address{City: "Toronto", Country: "Canada"},
+		Nickname: "anonymous",
+		Slash:    "slash",
+		Tilde:    "tilde",
+		SecondID: 2,
+		Raw:      "Toronto",
+		Token:    "secret",
+	}, got)
+
+	// The request body can still be read.
+	body, err := io.ReadAll(r.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleJSONPointerBody, string(body))
+}
+
+func TestDirectiveJSONPointer_Decode_WithBodyDirective(t *testing.T) {
+	type Input struct {
+		Name string `in:"jsonptr=/user/name"`
+		Body *struct {
+			User Address `json:"user"`
+		} `in:"body=json"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(`{"user":{"name":"ggicci","city":"Toronto"}}`))
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "ggicci", got.(*Input).Name)
+	assert.Equal(t, "Toronto", got.(*Input).Body.User.City)
+}
+
+func TestDirectiveJSONPointer_Decode_Errors(t *testing.T) {
+	co, err := New(JSONPointerInput{})
+	assert.NoError(t, err)
+
+	// Empty body.
+	r, _ := http.NewRequest("POST", "/", strings.NewReader(""))
+	_, err = co.Decode(r)
+	assert.ErrorContains(t, err, "missing required field")
+
+	// Malformed JSON.
+	r, _ = http.NewRequest("POST", "/", strings.NewReader(`{"user":`))
+	_, err = co.Decode(r)
+	assert.ErrorContains(t, err, "invalid JSON body")
+	var malformed *InvalidFieldError
+	assert.ErrorAs(t, err, &malformed)
+	assert.Equal(t, "/user/id", malformed.Key)
+	assert.ErrorIs(t, err, ErrMalformedRequest)
+
+	// Invalid value.
+	r, _ = http.NewRequest("POST", "/", strings.NewReader(`{"user":{"id":"abc"}}`))
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "ID", invalidField.Field)
+	assert.Equal(t, "jsonptr", invalidField.Directive)
+	assert.Equal(t, "/user/id", invalidField.Key)
+	assert.Equal(t, []string{"abc"}, invalidField.Value)
+}
+
+func TestDirectiveJSONPointer_New_Errors(t *testing.T) {
+	type InputInvalidPointer struct {
+		ID int `in:"jsonptr=user/id"`
+	}
+	_, err := New(InputInvalidPointer{})
+	assert.ErrorContains(t, err, "directive jsonptr: invalid JSON pointer \"user/id\"")
+
+	type InputInvalidEscape struct {
+		ID int `in:"jsonptr=/user/~2"`
+	}
+	_, err = New(InputInvalidEscape{})
+	assert.ErrorContains(t, err, "bad escape sequence")
+
+	type InputMissingPointer struct {
+		ID int `in:"jsonptr"`
+	}
+	_, err = New(InputMissingPointer{})
+	assert.ErrorContains(t, err, "directive jsonptr: missing JSON pointer")
+}
+
+func TestDirectiveJSONPointer_NewRequest(t *testing.T) {
+	registerMyDate()
+	defer unregisterMyDate()
+
+	type Input struct {
+		ID       int                  `in:"jsonptr=/user/id"`
+		Name     string               `in:"jsonptr=/user/name"`
+		Score    patch.Field[float64] `in:"jsonptr=/user/score"`
+		Tags     []string             `in:"jsonptr=/user/tags"`
+		Birthday time.Time            `in:"jsonptr=/user/birthday;coder=mydate"`
+		Address  *Address             `in:"jsonptr=/user/address;omitempty"`
+		Page     int                  `in:"query=page"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+	payload := &Input{
+		ID:       42,
+		Name:     "ggicci",
+		Tags:     []string{"a", "b"},
+		Birthday: time.Date(1991, 11, 10, 0, 0, 0, 0, time.UTC),
+		Page:     1,
+	}
+	req, err := co.NewRequest("POST", "/users", payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "page=1", req.URL.RawQuery)
+
+	var body map[string]any
+	assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+	assert.Equal(t, map[string]any{
+		"user": map[string]any{
+			"id":       float64(42),
+			"name":     "ggicci",
+			"tags":     []any{"a", "b"},
+			"birthday": "1991-11-10",
+		},
+	}, body)
+}
+
+func TestDirectiveJSONPointer_NewRequest_Errors(t *testing.T) {
+	type InputConflict struct {
+		Name string `in:"jsonptr=/name"`
+		Page int    `in:"form=page"`
+	}
+	co, err := New(InputConflict{})
+	assert.NoError(t, err)
+	_, err = co.NewRequest("POST", "/", &InputConflict{Name: "ggicci", Page: 1})
+	assert.ErrorContains(t, err, "cannot use both form and body directive")
+
+	type InputNonObject struct {
+		User string `in:"jsonptr=/user"`
+		Name string `in:"jsonptr=/user/name"`
+	}
+	co, err = New(InputNonObject{})
+	assert.NoError(t, err)
+	_, err = co.NewRequest("POST", "/", &InputNonObject{User: "ggicci", Name: "ggicci"})
+	assert.ErrorContains(t, err, "cannot set JSON pointer \"/user/name\"")
+}
+
+func TestLookupJSONPointer(t *testing.T) {
+	var document any
+	assert.NoError(t, json.Unmarshal([]byte(`{"a":[{"b":1}],"":{"c":2}}`), &document))
+
+	for pointer, expected := range map[string]any{
+		"":       document,
+		"/a/0/b": float64(1),
+		"//c":    float64(2),
+	} {
+		tokens, err := parseJSONPointer(pointer)
+		assert.NoError(t, err)
+		got, found := lookupJSONPointer(document, tokens)
+		assert.True(t, found, pointer)
+		assert.Equal(t, expected, got, pointer)
+	}
+
+	for _, pointer := range []string{"/a/01/b", "/a/-", "/a/1", "/a/0/b/c", "/x"} {
+		tokens, err := parseJSONPointer(pointer)
+		assert.NoError(t, err)
+		_, found := lookupJSONPointer(document, tokens)
+		assert.False(t, found, pointer)
+	}
+}
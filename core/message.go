@@ -0,0 +1,130 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// defaultLanguage is the language of the built-in messages. It's used when
+// none of the registered languages is acceptable by the client.
+const defaultLanguage = "en"
+
+// messageCatalogs are the message templates of the error codes, keyed by
+// language, then by code.
+var (
+	messageCatalogs = map[string]map[string]string{
+		defaultLanguage: {
+			CodeInvalid:              "The value is invalid.",
+			CodeRequired:             "This field is required.",
+			CodeNonzero:              "This field must not be empty.",
+			CodeInvalidBool:          "Must be a boolean.",
+			CodeInvalidInt:           "Must be an integer.",
+			CodeInvalidUint:          "Must be a non-negative integer.",
+			CodeInvalidFloat:         "Must be a number.",
+			CodeInvalidComplex:       "Must be a complex number.",
+			CodeInvalidTime:          "Must be a valid time.",
+			CodeOutOfRange:           "The number {value} is out of range.",
+			CodeTooSmall:             "Must be at least {min}.",
+			CodeTooBig:               "Must be at most {max}.",
+			CodeWrongLength:          "Must have a length of exactly {len}.",
+			CodeTooShort:             "Must have a length of at least {minlen}.",
+			CodeTooLong:              "Must have a length of at most {maxlen}.",
+			CodePatternMismatch:      "Must match the pattern {pattern}.",
+			CodeNotInEnum:            "Must be one of {enum}.",
+			CodeInvalidFormat:        "Must be a valid {format}.",
+			CodeInvalidETag:          "Must be a valid entity tag.",
+			CodeMalformedCredentials: "The credentials are malformed.",
+			CodeMalformedBody:        "The request body is malformed.",
+			CodeUnknownField:         "The request body contains an unknown field {name}.",
+			CodeBodyTooLarge:         "The request body is too large.",
+			CodeUnsupportedMediaType: "The media type of the request body is not supported.",
+			CodeUnknownBodyFormat:    "The request body can't be processed.",
+			CodeUnsupportedType:      "The value is invalid.",
+			CodeTypeMismatch:         "The value is invalid.",
+		},
+	}
+	messageLanguages = []string{defaultLanguage} // in the order of registration
+
+	reMessagePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+// RegisterMessages registers the message templates of the error codes for the
+// language lang, e.g. "en", "zh-CN". The templates of an already registered
+// language are merged, which also allows overriding the built-in English
+// messages. For example:
+//
+//	func init() {
+//		core.RegisterMessages("fr", map[string]string{
+//			core.CodeRequired: "Ce champ est obligatoire.",
+//			core.CodeTooLong:  "Doit comporter au plus {maxlen} caractères.",
+//		})
+//	}
+//
+// The placeholders, i.e. "{name}", are replaced by the Params of the error,
+// and "{field}" and "{key}" by the Field and Key of the error.
+func RegisterMessages(lang string, messages map[string]string) {
+	catalog := messageCatalogs[lang]
+	if catalog == nil {
+		catalog = make(map[string]string, len(messages))
+		messageCatalogs[lang] = catalog
+		messageLanguages = append(messageLanguages, lang)
+	}
+	for code, template := range messages {
+		catalog[code] = template
+	}
+}
+
+// LocalizeMessage returns the message of the error in the language preferred
+// by the client, which is negotiated by the Accept-Language header of the
+// request r among the registered languages, see RegisterMessages. The English
+// message is returned when no message of the preferred language is registered.
+// Returns an empty string if the error has no code.
+func LocalizeMessage(r *http.Request, e *InvalidFieldError) string {
+	if e.Code == "" {
+		return ""
+	}
+	var prefs LanguagePreferences
+	if prefs.FromStringSlice(r.Header.Values("Accept-Language")) != nil {
+		prefs = nil
+	}
+	template, ok := messageCatalogs[prefs.Negotiate(messageLanguages...)][e.Code]
+	if !ok {
+		template, ok = messageCatalogs[defaultLanguage][e.Code]
+	}
+	if !ok {
+		template = messageCatalogs[defaultLanguage][CodeInvalid]
+	}
+	return reMessagePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		switch value, ok := e.Params[name]; {
+		case ok:
+			return formatMessageParam(value)
+		case name == "field":
+			return e.Field
+		case name == "key":
+			return e.Key
+		}
+		return placeholder
+	})
+}
+
+func formatMessageParam(value any) string {
+	if values, ok := value.([]string); ok {
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// localizeFieldErrors returns the copies of the errors with the Message set,
+// see LocalizeMessage.
+func localizeFieldErrors(r *http.Request, errs MultiInvalidFieldError) MultiInvalidFieldError {
+	localized := make(MultiInvalidFieldError, len(errs))
+	for i, e := range errs {
+		copied := *e
+		copied.Message = LocalizeMessage(r, e)
+		localized[i] = &copied
+	}
+	return localized
+}
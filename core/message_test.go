@@ -0,0 +1,57 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalizeMessage(t *testing.T) {
+	RegisterMessages("fr", map[string]string{
+		CodeRequired: "Le champ {key} est obligatoire.",
+		CodeTooLong:  "Doit comporter au plus {maxlen} caractères.",
+	})
+	defer func() {
+		delete(messageCatalogs, "fr")
+		messageLanguages = messageLanguages[:len(messageLanguages)-1]
+	}()
+
+	required := &InvalidFieldError{Field: "Name", Key: "name", Code: CodeRequired}
+	tooLong := &InvalidFieldError{Code: CodeTooLong, Params: map[string]any{"maxlen": 3, "length": 5}}
+	notInEnum := &InvalidFieldError{Code: CodeNotInEnum, Params: map[string]any{"enum": []string{"asc", "desc"}}}
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	assert.Equal(t, "This field is required.", LocalizeMessage(r, required))
+	assert.Equal(t, "Must be one of asc, desc.", LocalizeMessage(r, notInEnum))
+	assert.Equal(t, "", LocalizeMessage(r, &InvalidFieldError{}))
+
+	r.Header.Set("Accept-Language", "fr-CA, fr;q=0.9, en;q=0.8")
+	assert.Equal(t, "Le champ name est obligatoire.", LocalizeMessage(r, required))
+	assert.Equal(t, "Doit comporter au plus 3 caractères.", LocalizeMessage(r, tooLong))
+	assert.Equal(t, "Must be one of asc, desc.", LocalizeMessage(r, notInEnum)) // falls back to English
+	assert.Equal(t, "The value is invalid.", LocalizeMessage(r, &InvalidFieldError{Code: "unknown"}))
+
+	r.Header.Set("Accept-Language", "de")
+	assert.Equal(t, "This field is required.", LocalizeMessage(r, required))
+}
+
+func TestDefaultErrorHandler_LocalizedMessage(t *testing.T) {
+	RegisterMessages("en", map[string]string{CodeInvalidInt: "Enter a whole number."})
+	defer RegisterMessages("en", map[string]string{CodeInvalidInt: "Must be an integer."})
+
+	type Input struct {
+		Page int `in:"query=page"`
+	}
+	co, err := New(Input{}, WithErrorHandler(defaultErrorHandler))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/?page=x", nil)
+	_, err = co.Decode(r)
+	rw := httptest.NewRecorder()
+	co.GetErrorHandler()(rw, r, err)
+	assert.Equal(t, 422, rw.Code)
+	assert.Contains(t, rw.Body.String(), `"code":"invalid_int"`)
+	assert.Contains(t, rw.Body.String(), `"message":"Enter a whole number."`)
+}
@@ -0,0 +1,368 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"time"
+)
+
+// MessagePackBody is the BodySerializer of the "msgpack" body format, see
+// https://github.com/msgpack/msgpack/blob/master/spec.md. The struct fields are
+// named by the "msgpack" tag, or the "json" tag. time.Time is encoded as the
+// timestamp extension type.
+type MessagePackBody struct{}
+
+func (de *MessagePackBody) Decode(src io.Reader, dst any) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return io.EOF
+	}
+	d := &msgpackDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return err
+	}
+	return fromGeneric(value, reflect.ValueOf(dst).Elem(), "msgpack")
+}
+
+func (en *MessagePackBody) Encode(src any) (io.Reader, error) {
+	value, err := toGeneric(reflect.ValueOf(src), "msgpack")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := encodeMsgpack(&buf, value); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+const msgpackTimestampType = -1
+
+func encodeMsgpack(buf *bytes.Buffer, v any) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if value {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int64:
+		if value >= 0 {
+			encodeMsgpackUint(buf, uint64(value))
+		} else {
+			encodeMsgpackInt(buf, value)
+		}
+	case uint64:
+		encodeMsgpackUint(buf, value)
+	case float32:
+		buf.WriteByte(0xca)
+		buf.Write(binary.BigEndian.AppendUint32(nil, math.Float32bits(value)))
+	case float64:
+		buf.WriteByte(0xcb)
+		buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(value)))
+	case string:
+		encodeMsgpackHead(buf, len(value), 0xa0, 32, 0xd9, 0xda, 0xdb)
+		buf.WriteString(value)
+	case []byte:
+		encodeMsgpackHead(buf, len(value), 0, 0, 0xc4, 0xc5, 0xc6)
+		buf.Write(value)
+	case time.Time:
+		encodeMsgpackTimestamp(buf, value)
+	case []any:
+		encodeMsgpackHead(buf, len(value), 0x90, 16, 0, 0xdc, 0xdd)
+		for _, elem := range value {
+			if err := encodeMsgpack(buf, elem); err != nil {
+				return err
+			}
+		}
+	case genericMap:
+		encodeMsgpackHead(buf, len(value), 0x80, 16, 0, 0xde, 0xdf)
+		for _, entry := range value {
+			if err := encodeMsgpack(buf, entry.Key); err != nil {
+				return err
+			}
+			if err := encodeMsgpack(buf, entry.Value); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+	}
+	return nil
+}
+
+func encodeMsgpackUint(buf *bytes.Buffer, n uint64) {
+	switch {
+	case n < 128:
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint8:
+		buf.Write([]byte{0xcc, byte(n)})
+	case n <= math.MaxUint16:
+		buf.Write(binary.BigEndian.AppendUint16([]byte{0xcd}, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.Write(binary.BigEndian.AppendUint32([]byte{0xce}, uint32(n)))
+	default:
+		buf.Write(binary.BigEndian.AppendUint64([]byte{0xcf}, n))
+	}
+}
+
+func encodeMsgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= -32:
+		buf.WriteByte(byte(n))
+	case n >= math.MinInt8:
+		buf.Write([]byte{0xd0, byte(n)})
+	case n >= math.MinInt16:
+		buf.Write(binary.BigEndian.AppendUint16([]byte{0xd1}, uint16(n)))
+	case n >= math.MinInt32:
+		buf.Write(binary.BigEndian.AppendUint32([]byte{0xd2}, uint32(n)))
+	default:
+		buf.Write(binary.BigEndian.AppendUint64([]byte{0xd3}, uint64(n)))
+	}
+}
+
+// encodeMsgpackHead writes the type and the length of a string, binary, array
+// or map. fix is the type of the fix format, which is used when n < fixMax. A
+// zero type means that the format is not available.
+func encodeMsgpackHead(buf *bytes.Buffer, n int, fix byte, fixMax int, type8, type16, type32 byte) {
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case type8 != 0 && n <= math.MaxUint8:
+		buf.Write([]byte{type8, byte(n)})
+	case n <= math.MaxUint16:
+		buf.Write(binary.BigEndian.AppendUint16([]byte{type16}, uint16(n)))
+	default:
+		buf.Write(binary.BigEndian.AppendUint32([]byte{type32}, uint32(n)))
+	}
+}
+
+func encodeMsgpackTimestamp(buf *bytes.Buffer, t time.Time) {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	switch {
+	case sec >= 0 && sec <= math.MaxUint32 && nsec == 0: // timestamp 32
+		buf.Write([]byte{0xd6, byte(msgpackTimestampType & 0xff)})
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(sec)))
+	case sec >= 0 && sec < 1<<34: // timestamp 64
+		buf.Write([]byte{0xd7, byte(msgpackTimestampType & 0xff)})
+		buf.Write(binary.BigEndian.AppendUint64(nil, nsec<<34|uint64(sec)))
+	default: // timestamp 96
+		buf.Write([]byte{0xc7, 12, byte(msgpackTimestampType & 0xff)})
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(nsec)))
+		buf.Write(binary.BigEndian.AppendUint64(nil, uint64(sec)))
+	}
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) read(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) readUint(size int) (uint64, error) {
+	b, err := d.read(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// readLength reads a length of the given size. The length is checked against
+// the remaining data, each item takes minSize bytes at least.
+func (d *msgpackDecoder) readLength(size, minSize int) (int, error) {
+	n, err := d.readUint(size)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)-d.pos)/uint64(minSize) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return int(n), nil
+}
+
+func (d *msgpackDecoder) decode(depth int) (any, error) {
+	if depth > maxGenericDepth {
+		return nil, fmt.Errorf("%w: msgpack: %w", ErrMalformedRequest, errGenericTooDeep)
+	}
+	head, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	t := head[0]
+	switch {
+	case t <= 0x7f: // positive fixint
+		return int64(t), nil
+	case t >= 0xe0: // negative fixint
+		return int64(int8(t)), nil
+	case t >= 0xa0 && t <= 0xbf: // fixstr
+		return d.decodeString(int(t & 0x1f))
+	case t >= 0x90 && t <= 0x9f: // fixarray
+		return d.decodeArray(int(t&0x0f), depth)
+	case t >= 0x80 && t <= 0x8f: // fixmap
+		return d.decodeMap(int(t&0x0f), depth)
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := d.readUint(1 << (t - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if n <= math.MaxInt64 {
+			return int64(n), nil
+		}
+		return n, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (t - 0xd0)
+		n, err := d.readUint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, nil // sign extension
+	case 0xca:
+		n, err := d.readUint(4)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float32frombits(uint32(n)), nil
+	case 0xcb:
+		n, err := d.readUint(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(n), nil
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.readLength(1<<(t-0xd9), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeString(n)
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.readLength(1<<(t-0xc4), 1)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.read(n)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xdc, 0xdd:
+		n, err := d.readLength(2<<(t-0xdc), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeArray(n, depth)
+	case 0xde, 0xdf:
+		n, err := d.readLength(2<<(t-0xde), 2)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeMap(n, depth)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: // fixext 1, 2, 4, 8, 16
+		return d.decodeExt(1 << (t - 0xd4))
+	case 0xc7, 0xc8, 0xc9: // ext 8, 16, 32
+		n, err := d.readLength(1<<(t-0xc7), 1)
+		if err != nil {
+			return nil, err
+		}
+		return d.decodeExt(n)
+	}
+	return nil, fmt.Errorf("%w: msgpack: invalid type byte 0x%02x", ErrMalformedRequest, t)
+}
+
+func (d *msgpackDecoder) decodeString(n int) (any, error) {
+	b, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) decodeArray(n, depth int) (any, error) {
+	elems := make([]any, n)
+	for i := range elems {
+		elem, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		elems[i] = elem
+	}
+	return elems, nil
+}
+
+func (d *msgpackDecoder) decodeMap(n, depth int) (any, error) {
+	m := make(genericMap, n)
+	for i := range m {
+		key, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		m[i] = genericEntry{key, value}
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) decodeExt(n int) (any, error) {
+	typ, err := d.read(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.read(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != msgpackTimestampType {
+		return nil, fmt.Errorf("%w: msgpack: extension type %d", ErrUnsupportedType, int8(typ[0]))
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&(1<<34-1)), int64(v>>34)).UTC(), nil
+	case 12:
+		nsec := binary.BigEndian.Uint32(data[:4])
+		sec := int64(binary.BigEndian.Uint64(data[4:]))
+		return time.Unix(sec, int64(nsec)).UTC(), nil
+	}
+	return nil, fmt.Errorf("%w: msgpack: invalid timestamp length %d", ErrMalformedRequest, n)
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessagePack_Values(t *testing.T) {
+	for _, c := range []struct {
+		value any
+		hex   string
+	}{
+		{nil, "c0"},
+		{true, "c3"},
+		{false, "c2"},
+		{int64(0), "00"},
+		{int64(127), "7f"},
+		{int64(128), "cc80"},
+		{int64(65536), "ce00010000"},
+		{int64(-1), "ff"},
+		{int64(-33), "d0df"},
+		{int64(-129), "d1ff7f"},
+		{int64(-1 << 40), "d3ffffff0000000000"},
+		{uint64(1 << 63), "cf8000000000000000"},
+		{float32(1.5), "ca3fc00000"},
+		{1.5, "cb3ff8000000000000"},
+		{"", "a0"},
+		{"hello", "a568656c6c6f"},
+		{strings.Repeat("x", 32), "d920" + strings.Repeat("78", 32)},
+		{[]byte{1, 2}, "c4020102"},
+		{[]any{int64(1), "a"}, "9201a161"},
+		{genericMap{{"a", int64(1)}}, "81a16101"},
+		{time.Unix(1, 0).UTC(), "d6ff00000001"},
+		{time.Unix(1, 2).UTC(), "d7ff0000000800000001"},
+		{time.Unix(-1, 0).UTC(), "c70cff00000000ffffffffffffffff"},
+	} {
+		var buf bytes.Buffer
+		assert.NoError(t, encodeMsgpack(&buf, c.value))
+		assert.Equal(t, c.hex, hex.EncodeToString(buf.Bytes()), c.value)
+
+		data, _ := hex.DecodeString(c.hex)
+		d := &msgpackDecoder{data: data}
+		got, err := d.decode(0)
+		assert.NoError(t, err)
+		assert.Equal(t, c.value, got, c.hex)
+	}
+}
+
+func TestMessagePack_Malformed(t *testing.T) {
+	for _, data := range []string{"", "c1", "a5686568", "dc0010", "dfffffffff", "d5ff0000"} {
+		var v any
+		b, _ := hex.DecodeString(data)
+		err := (&MessagePackBody{}).Decode(bytes.NewReader(b), &v)
+		assert.Error(t, err, data)
+		assert.Equal(t, 400, DefaultErrorStatusMapper(classifyRequestError(err)), data)
+	}
+
+	// Nesting too deep.
+	var v any
+	err := (&MessagePackBody{}).Decode(bytes.NewReader(bytes.Repeat([]byte{0x91}, maxGenericDepth+2)), &v)
+	assert.ErrorIs(t, err, ErrMalformedRequest)
+}
+
+type TelemetryInput struct {
+	Payload *TelemetryPayload `in:"body=msgpack"`
+}
+
+func TestMessagePack_Body(t *testing.T) {
+	co, err := New(TelemetryInput{})
+	assert.NoError(t, err)
+
+	input := &TelemetryInput{Payload: sampleTelemetryPayload()}
+	req, err := co.NewRequest("POST", "/telemetry", input)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/msgpack", req.Header.Get("Content-Type"))
+
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, input, got)
+
+	// A missing or nil patch field is not valid.
+	var body bytes.Buffer
+	assert.NoError(t, encodeMsgpack(&body, genericMap{{"id", int64(7)}, {"note", nil}}))
+	req, _ = http.NewRequest("POST", "/telemetry", io.NopCloser(&body))
+	req.Header.Set("Content-Type", "application/msgpack")
+	got, err = co.Decode(req)
+	assert.NoError(t, err)
+	payload := got.(*TelemetryInput).Payload
+	assert.Equal(t, uint64(7), payload.ID)
+	assert.Equal(t, patch.Field[string]{}, payload.Note)
+	assert.False(t, payload.Threshold.Valid)
+}
@@ -16,16 +16,18 @@ import "errors"
 // Unlike the "required" executor, the "nonzero" executor checks the value of the field.
 type DirectiveNonzero struct{}
 
+var errZeroValue = errors.New("zero value")
+
 func (*DirectiveNonzero) Decode(rtm *DirectiveRuntime) error {
 	if rtm.Value.Elem().IsZero() {
-		return errors.New("zero value")
+		return NewCodedError(CodeNonzero, nil, errZeroValue)
 	}
 	return nil
 }
 
 func (*DirectiveNonzero) Encode(rtm *DirectiveRuntime) error {
 	if rtm.Value.IsZero() {
-		return errors.New("zero value")
+		return NewCodedError(CodeNonzero, nil, errZeroValue)
 	}
 	return nil
 }
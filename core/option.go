@@ -2,6 +2,9 @@ package core
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
 )
 
 const minimumMaxMemory = int64(1 << 10)  // 1KB
@@ -49,3 +52,95 @@ func WithNestedDirectivesEnabled(enable bool) Option {
 		return nil
 	}
 }
+
+// WithTrustedProxies sets the proxies whose forwarding header is trusted by the
+// "clientip" directive, see WithTrustedProxyHeader. Each proxy is either an IP
+// address or a CIDR, e.g. "192.0.2.1", "10.0.0.0/8".
+func WithTrustedProxies(proxies ...string) Option {
+	return func(c *Core) error {
+		prefixes := make([]netip.Prefix, 0, len(proxies))
+		for _, proxy := range proxies {
+			prefix, err := parseTrustedProxy(proxy)
+			if err != nil {
+				return err
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		c.trustedProxies = prefixes
+		return nil
+	}
+}
+
+// WithTrustedProxyHeader sets the forwarding header set by the trusted proxies,
+// which is one of "Forwarded" (RFC 7239), "X-Forwarded-For" and "X-Real-IP".
+// The default is "X-Forwarded-For". The other forwarding headers are ignored,
+// since they may come from the client as is, see WithTrustedProxies.
+func WithTrustedProxyHeader(header string) Option {
+	return func(c *Core) error {
+		canonical := http.CanonicalHeaderKey(header)
+		if canonical == "X-Real-Ip" {
+			canonical = "X-Real-IP"
+		}
+		if !isForwardingHeader(canonical) {
+			return fmt.Errorf("invalid trusted proxy header %q", header)
+		}
+		c.trustedProxyHeader = canonical
+		return nil
+	}
+}
+
+// WithCollectAllErrors makes the decoding continue with the remaining fields
+// after a field fails. All the failures are returned as a
+// MultiInvalidFieldError. By default, the decoding stops at the first failure,
+// which is returned as an InvalidFieldError.
+func WithCollectAllErrors(enable bool) Option {
+	return func(c *Core) error {
+		c.collectAllErrors = enable
+		return nil
+	}
+}
+
+// WithErrorStatusMapper overrides the mapping from the decoding errors to the
+// HTTP status codes used by the default error handler, see ErrorStatusCode and
+// DefaultErrorStatusMapper.
+func WithErrorStatusMapper(mapper ErrorStatusMapper) Option {
+	return func(c *Core) error {
+		if mapper == nil {
+			return errors.New("nil error status mapper")
+		}
+		c.errorStatusMapper = mapper
+		return nil
+	}
+}
+
+// WithMaxBodyBytes limits the size of the request body by http.MaxBytesReader.
+// Reading more than maxBytes from the body, e.g. by parsing the form or the
+// "body" directive, fails with an error of ErrRequestTooLarge, which results in
+// a 413 response by default. The limit also applies to the downstream handlers.
+// Zero means unlimited. A field can be limited further by the "maxbytes"
+// directive.
+func WithMaxBodyBytes(maxBytes int64) Option {
+	return func(c *Core) error {
+		if maxBytes < 0 {
+			return errors.New("negative max body bytes")
+		}
+		c.maxBodyBytes = maxBytes
+		return nil
+	}
+}
+
+// WithBodyReplay makes the request body replayable. When the size of the body
+// does not exceed maxBytes, the body is buffered in memory before decoding.
+// Which allows multiple "body" directives in the input struct to decode the
+// same payload. And after decoding, http.Request.Body and
+// http.Request.GetBody are restored, so the body can be read again by the
+// downstream handlers. Larger bodies are not replayable. Zero disables it.
+func WithBodyReplay(maxBytes int64) Option {
+	return func(c *Core) error {
+		if maxBytes < 0 {
+			return errors.New("negative max bytes of body replay")
+		}
+		c.bodyReplayMaxBytes = maxBytes
+		return nil
+	}
+}
@@ -2,7 +2,10 @@ package core
 
 import (
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -23,6 +26,32 @@ func TestWithErrorHandler(t *testing.T) {
 	assert.ErrorContains(t, err, "nil error handler")
 }
 
+func TestWithCollectAllErrors(t *testing.T) {
+	co, _ := New(ProductQuery{})
+	assert.False(t, co.collectAllErrors)
+
+	co, _ = New(ProductQuery{}, WithCollectAllErrors(true))
+	assert.True(t, co.collectAllErrors)
+}
+
+func TestWithErrorStatusMapper(t *testing.T) {
+	co, _ := New(ProductQuery{})
+	assert.Nil(t, co.errorStatusMapper)
+
+	co, err := New(ProductQuery{},
+		WithErrorHandler(defaultErrorHandler),
+		WithErrorStatusMapper(func(error) int { return http.StatusTeapot }),
+	)
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("GET", "/", nil)
+	rw := httptest.NewRecorder()
+	co.GetErrorHandler()(rw, r, assert.AnError)
+	assert.Equal(t, http.StatusTeapot, rw.Code)
+
+	_, err = New(ProductQuery{}, WithErrorStatusMapper(nil))
+	assert.ErrorContains(t, err, "nil error status mapper")
+}
+
 func TestWithMaxMemory(t *testing.T) {
 	// Use the default max memory.
 	co, _ := New(ProductQuery{})
@@ -56,6 +85,78 @@ func TestEnableNestedDirectives(t *testing.T) {
 	assert.Equal(t, true, co.enableNestedDirectives)
 }
 
+func TestWithTrustedProxies(t *testing.T) {
+	co, err := New(ProductQuery{}, WithTrustedProxies("10.0.0.1/8", "192.0.2.1", "2001:db8::/32"))
+	assert.NoError(t, err)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}, co.trustedProxies)
+
+	_, err = New(ProductQuery{}, WithTrustedProxies("10.0.0.0/33"))
+	assert.ErrorContains(t, err, "invalid trusted proxy \"10.0.0.0/33\"")
+	_, err = New(ProductQuery{}, WithTrustedProxies("localhost"))
+	assert.ErrorContains(t, err, "invalid trusted proxy \"localhost\"")
+}
+
+func TestWithTrustedProxyHeader(t *testing.T) {
+	co, err := New(ProductQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, "", co.trustedProxyHeader) // X-Forwarded-For
+
+	for header, expected := range map[string]string{
+		"forwarded":       "Forwarded",
+		"X-Forwarded-For": "X-Forwarded-For",
+		"x-real-ip":       "X-Real-IP",
+	} {
+		co, err = New(ProductQuery{}, WithTrustedProxyHeader(header))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, co.trustedProxyHeader)
+	}
+
+	_, err = New(ProductQuery{}, WithTrustedProxyHeader("X-Client-IP"))
+	assert.ErrorContains(t, err, "invalid trusted proxy header \"X-Client-IP\"")
+}
+
+func TestWithMaxBodyBytes(t *testing.T) {
+	co, err := New(BodyPayloadInJSON{}, WithMaxBodyBytes(16))
+	assert.NoError(t, err)
+	_, err = co.Decode(newJSONRequest(sampleBodyPayloadInJSONText))
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, DefaultErrorStatusMapper(err))
+
+	// The form parsing is limited as well.
+	type FormInput struct {
+		Name string `in:"form=name"`
+	}
+	co, err = New(FormInput{}, WithMaxBodyBytes(16))
+	assert.NoError(t, err)
+	r, _ := http.NewRequest("POST", "/", strings.NewReader("name="+strings.Repeat("x", 32)))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrFailedToParseRequestForm)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, DefaultErrorStatusMapper(err))
+
+	// With body replay.
+	co, err = New(BodyPayloadInJSON{}, WithMaxBodyBytes(16), WithBodyReplay(1024))
+	assert.NoError(t, err)
+	_, err = co.Decode(newJSONRequest(sampleBodyPayloadInJSONText))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, DefaultErrorStatusMapper(err))
+
+	_, err = New(BodyPayloadInJSON{}, WithMaxBodyBytes(-1))
+	assert.ErrorContains(t, err, "negative max body bytes")
+}
+
+func TestWithBodyReplay(t *testing.T) {
+	co, err := New(ProductQuery{}, WithBodyReplay(1<<20))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1<<20), co.bodyReplayMaxBytes)
+
+	_, err = New(ProductQuery{}, WithBodyReplay(-1))
+	assert.ErrorContains(t, err, "negative max bytes of body replay")
+}
+
 func equalFuncs(expected, actual any) bool {
 	return reflect.ValueOf(expected).Pointer() == reflect.ValueOf(actual).Pointer()
 }
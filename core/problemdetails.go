@@ -0,0 +1,146 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ProblemDetails is the problem details object of RFC 9457. The invalid
+// fields are listed in the "errors" extension member.
+type ProblemDetails struct {
+	XMLName  xml.Name        `json:"-" xml:"urn:ietf:rfc:7807 problem"`
+	Type     string          `json:"type" xml:"type"`
+	Title    string          `json:"title" xml:"title"`
+	Status   int             `json:"status" xml:"status"`
+	Detail   string          `json:"detail,omitempty" xml:"detail,omitempty"`
+	Instance string          `json:"instance,omitempty" xml:"instance,omitempty"`
+	Errors   []*ProblemError `json:"errors,omitempty" xml:"errors>i,omitempty"`
+}
+
+// ProblemError is an element of the "errors" extension member of
+// ProblemDetails, which describes an invalid field.
+type ProblemError struct {
+	Field     string `json:"field" xml:"field"`
+	Directive string `json:"directive" xml:"directive"`
+	Key       string `json:"key" xml:"key"`
+	Value     any    `json:"value" xml:"-"`
+	Error     string `json:"error" xml:"error"`
+
+	// Code, Params and Message are the same as of InvalidFieldError. The
+	// Message is localized by the Accept-Language header, see
+	// RegisterMessages.
+	Code    string         `json:"code,omitempty" xml:"code,omitempty"`
+	Params  map[string]any `json:"params,omitempty" xml:"-"`
+	Message string         `json:"message,omitempty" xml:"message,omitempty"`
+
+	// XMLValue is the string values of Value. An XML array is a list of "i"
+	// elements, see RFC 9457, Appendix B.
+	XMLValue []string `json:"-" xml:"value>i,omitempty"`
+}
+
+// ProblemDetailsOptions customizes the ProblemDetailsErrorHandler.
+type ProblemDetailsOptions struct {
+	// Type is the "type" member of the problems of invalid fields, a URI
+	// reference that identifies the problem type. Defaults to "about:blank".
+	Type string
+
+	// Instance returns the "instance" member of a problem. Defaults to the
+	// request URI.
+	Instance func(r *http.Request) string
+}
+
+// ProblemDetailsErrorHandler creates an ErrorHandler which renders the errors
+// as the problem details of RFC 9457, e.g.
+//
+//	httpin.NewInput(ListUsersInput{}, httpin.Option.WithErrorHandler(
+//		core.ProblemDetailsErrorHandler(core.ProblemDetailsOptions{}),
+//	))
+//
+// The response is "application/problem+json" by default, and
+// "application/problem+xml" when the client prefers XML by the Accept header.
+// The status is resolved by ErrorStatusCode. Invalid fields are listed in the
+// "errors" extension member, see WithCollectAllErrors. Server errors, i.e.
+// 5xx, are responded without details.
+func ProblemDetailsErrorHandler(opts ProblemDetailsOptions) ErrorHandler {
+	return func(rw http.ResponseWriter, r *http.Request, err error) {
+		problem := newProblemDetails(r, err, opts)
+		var accept AcceptList
+		if accept.FromStringSlice(r.Header.Values("Accept")) == nil &&
+			strings.HasSuffix(accept.Negotiate(
+				"application/problem+json", "application/problem+xml",
+				"application/json", "application/xml", "text/xml",
+			), "xml") {
+			rw.Header().Set("Content-Type", "application/problem+xml")
+			rw.WriteHeader(problem.Status)
+			rw.Write([]byte(xml.Header))
+			xml.NewEncoder(rw).Encode(problem)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/problem+json")
+		rw.WriteHeader(problem.Status)
+		json.NewEncoder(rw).Encode(problem)
+	}
+}
+
+func newProblemDetails(r *http.Request, err error, opts ProblemDetailsOptions) *ProblemDetails {
+	problem := &ProblemDetails{Type: "about:blank"}
+	if opts.Instance != nil {
+		problem.Instance = opts.Instance(r)
+	} else {
+		problem.Instance = r.URL.RequestURI()
+	}
+
+	problem.setStatus(ErrorStatusCode(r, err))
+	if problem.Status >= http.StatusInternalServerError {
+		return problem // no details of server errors
+	}
+
+	var (
+		invalidFields MultiInvalidFieldError
+		invalidField  *InvalidFieldError
+	)
+	switch {
+	case errors.As(err, &invalidFields):
+	case errors.As(err, &invalidField):
+		invalidFields = MultiInvalidFieldError{invalidField}
+	default:
+		problem.Detail = err.Error()
+		return problem
+	}
+
+	if opts.Type != "" {
+		problem.Type = opts.Type
+	}
+	switch {
+	case len(invalidFields) > 1:
+		problem.Detail = fmt.Sprintf("%d invalid fields", len(invalidFields))
+	case invalidFields[0].Field == "": // returned by Validator
+		problem.Detail = invalidFields[0].ErrorMessage
+	default:
+		problem.Detail = fmt.Sprintf("invalid field %q", invalidFields[0].Field)
+	}
+	for _, e := range localizeFieldErrors(r, invalidFields) {
+		xmlValue, _ := e.Value.([]string)
+		problem.Errors = append(problem.Errors, &ProblemError{
+			Field:     e.Field,
+			Directive: e.Directive,
+			Key:       e.Key,
+			Value:     e.Value,
+			Error:     e.ErrorMessage,
+			Code:      e.Code,
+			Params:    e.Params,
+			Message:   e.Message,
+			XMLValue:  xmlValue,
+		})
+	}
+	return problem
+}
+
+func (p *ProblemDetails) setStatus(status int) {
+	p.Status = status
+	p.Title = http.StatusText(status)
+}
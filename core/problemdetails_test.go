@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ProblemInput struct {
+	Page    int    `in:"query=page;min=1"`
+	PerPage int    `in:"query=per_page"`
+	Token   string `in:"header=X-Token;required"`
+}
+
+func TestProblemDetailsErrorHandler_JSON(t *testing.T) {
+	handler := ProblemDetailsErrorHandler(ProblemDetailsOptions{})
+	co, err := New(ProblemInput{}, WithCollectAllErrors(true), WithErrorHandler(handler))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/users?page=0&per_page=x", nil)
+	_, err = co.Decode(r)
+	rw := httptest.NewRecorder()
+	co.GetErrorHandler()(rw, r, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	assert.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Unprocessable Entity",
+		"status": 422,
+		"detail": "3 invalid fields",
+		"instance": "/users?page=0&per_page=x",
+		"errors": [
+			{"field": "Page", "directive": "min", "key": "", "value": null, "error": "resolve field \"Page (int)\" failed: execute directive \"min\" with args [1] failed: value is less than the minimum 1",
+				"code": "too_small", "params": {"min": 1}, "message": "Must be at least 1."},
+			{"field": "PerPage", "directive": "query", "key": "per_page", "value": ["x"], "error": "resolve field \"PerPage (int)\" failed: execute directive \"query\" with args [per_page] failed: strconv.Atoi: parsing \"x\": invalid syntax",
+				"code": "invalid_int", "params": {"value": "x"}, "message": "Must be an integer."},
+			{"field": "Token", "directive": "required", "key": "", "value": null, "error": "resolve field \"Token (string)\" failed: execute directive \"required\" with args [] failed: missing required field",
+				"code": "required", "message": "This field is required."}
+		]
+	}`, rw.Body.String())
+}
+
+func TestProblemDetailsErrorHandler_XML(t *testing.T) {
+	handler := ProblemDetailsErrorHandler(ProblemDetailsOptions{
+		Type:     "https://example.com/probs/invalid-input",
+		Instance: func(r *http.Request) string { return "urn:request:42" },
+	})
+	co, err := New(ProblemInput{}, WithErrorHandler(handler))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/users?page=x", nil)
+	r.Header.Set("Accept", "application/json;q=0.5, application/xml")
+	_, err = co.Decode(r)
+	rw := httptest.NewRecorder()
+	co.GetErrorHandler()(rw, r, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	assert.Equal(t, "application/problem+xml", rw.Header().Get("Content-Type"))
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<problem xmlns="urn:ietf:rfc:7807">`+
+		`<type>https://example.com/probs/invalid-input</type>`+
+		`<title>Unprocessable Entity</title>`+
+		`<status>422</status>`+
+		`<detail>invalid field &#34;Page&#34;</detail>`+
+		`<instance>urn:request:42</instance>`+
+		`<errors><i><field>Page</field><directive>query</directive><key>page</key><error>resolve field &#34;Page (int)&#34; failed: execute directive &#34;query&#34; with args [page] failed: strconv.Atoi: parsing &#34;x&#34;: invalid syntax</error><code>invalid_int</code><message>Must be an integer.</message><value><i>x</i></value></i></errors>`+
+		`</problem>`, rw.Body.String())
+}
+
+func TestProblemDetailsErrorHandler_OtherErrors(t *testing.T) {
+	handler := ProblemDetailsErrorHandler(ProblemDetailsOptions{Type: "https://example.com/probs/invalid-input"})
+	r, _ := http.NewRequest("POST", "/users", nil)
+
+	rw := httptest.NewRecorder()
+	handler(rw, r, fmt.Errorf("%w: %w", ErrFailedToParseRequestForm, assert.AnError))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "failed to parse request form: assert.AnError general error for testing",
+		"instance": "/users"
+	}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	r.Header.Set("Accept", "invalid")
+	handler(rw, r, assert.AnError)
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	assert.Equal(t, "application/problem+json", rw.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "about:blank",
+		"title": "Internal Server Error",
+		"status": 500,
+		"instance": "/users"
+	}`, rw.Body.String())
+}
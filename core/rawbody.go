@@ -0,0 +1,149 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/ggicci/httpin/internal"
+	"github.com/ggicci/owl"
+)
+
+// The raw body formats bind the request body to a field as is, without going
+// through a BodySerializer. The field type must be one of []byte, string,
+// io.Reader and io.ReadCloser. For example:
+//
+//	type WebhookInput struct {
+//		Signature string        `in:"header=X-Signature"`
+//		Payload   io.ReadCloser `in:"body=raw;maxbytes=1048576"`
+//	}
+//
+// When decoding, an io.Reader or io.ReadCloser field is set to the request
+// body, which is streamed rather than buffered. The reading errors, e.g.
+// *http.MaxBytesError, are returned by the reader. The other types read the
+// whole body.
+//
+// When encoding, the field becomes the request body. The Content-Type defaults
+// to "application/octet-stream" for "raw", and "text/plain; charset=utf-8" for
+// "text", which can be overridden by the "type" directive, e.g.
+// `in:"body=raw;type=image/png"`. A nil io.Reader field leaves the body unset.
+const (
+	bodyFormatRaw  = "raw"
+	bodyFormatText = "text"
+)
+
+var (
+	rawBodyContentTypes = map[string]string{
+		bodyFormatRaw:  "application/octet-stream",
+		bodyFormatText: "text/plain; charset=utf-8",
+	}
+
+	readerType     = internal.TypeOf[io.Reader]()
+	readCloserType = internal.TypeOf[io.ReadCloser]()
+)
+
+func isRawBodyFormat(format string) bool {
+	_, ok := rawBodyContentTypes[format]
+	return ok
+}
+
+func isRawBodyType(typ reflect.Type) bool {
+	switch {
+	case typ == readerType, typ == readCloserType:
+		return true
+	case typ.Kind() == reflect.String:
+		return true
+	case typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8:
+		return true
+	}
+	return false
+}
+
+// validateBodyDirective ensures that a raw body format is the only format of
+// the "body" directive, and the field type is supported by it.
+func validateBodyDirective(r *owl.Resolver) error {
+	d := r.GetDirective("body")
+	if d == nil {
+		return nil
+	}
+	for _, format := range d.Argv {
+		format = strings.ToLower(format)
+		if !isRawBodyFormat(format) {
+			continue
+		}
+		if len(d.Argv) > 1 {
+			return fmt.Errorf("directive body: format %q cannot be combined with other formats", format)
+		}
+		if !isRawBodyType(r.Type) {
+			return fmt.Errorf("directive body: %w: format %q requires type []byte, string, io.Reader or io.ReadCloser, got %q",
+				ErrTypeMismatch, format, r.Type)
+		}
+	}
+	return nil
+}
+
+func decodeRawBody(rtm *DirectiveRuntime) error {
+	body, err := rtm.getRequestBodyReader()
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		body = http.NoBody
+	}
+	body = rtm.getBodyOptions().limit(body)
+
+	field := rtm.Value.Elem()
+	switch field.Type() {
+	case readerType:
+		field.Set(reflect.ValueOf(body))
+		return nil
+	case readCloserType:
+		field.Set(reflect.ValueOf(toReadCloser(body)))
+		return nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return classifyRequestError(err)
+	}
+	if field.Kind() == reflect.String {
+		field.SetString(string(data))
+	} else {
+		field.SetBytes(data)
+	}
+	return nil
+}
+
+func encodeRawBody(rtm *DirectiveRuntime, format string) error {
+	var body io.Reader
+	field := rtm.Value
+	switch {
+	case field.Type() == readerType, field.Type() == readCloserType:
+		if field.IsNil() {
+			return nil
+		}
+		body = field.Interface().(io.Reader)
+	case field.Kind() == reflect.String:
+		body = strings.NewReader(field.String())
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+		body = bytes.NewReader(field.Bytes())
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, field.Type())
+	}
+
+	rb := rtm.GetRequestBuilder()
+	rb.SetBody(format, toReadCloser(body))
+	rb.contentType = rtm.getBodyOptions().contentType(rawBodyContentTypes[format])
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+func toReadCloser(r io.Reader) io.ReadCloser {
+	if rc, ok := r.(io.ReadCloser); ok {
+		return rc
+	}
+	return io.NopCloser(r)
+}
@@ -0,0 +1,202 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type RawBodyInput struct {
+	Signature string `in:"header=X-Signature"`
+	Payload   []byte `in:"body=raw"`
+}
+
+type TextBodyInput struct {
+	Log string `in:"body=text;maxbytes=16"`
+}
+
+type StreamBodyInput struct {
+	Payload io.Reader `in:"body=raw;type=image/png"`
+}
+
+type StreamCloserBodyInput struct {
+	Payload io.ReadCloser `in:"body=raw;maxbytes=8"`
+}
+
+// countingReader counts the bytes read from it.
+type countingReader struct {
+	io.Reader
+	n int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += n
+	return n, err
+}
+
+func TestRawBody_Bytes(t *testing.T) {
+	co, err := New(RawBodyInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/hooks", bytes.NewReader([]byte{0xde, 0xad, 0xbe, 0xef}))
+	r.Header.Set("Content-Type", "application/x-anything")
+	r.Header.Set("X-Signature", "sha256=abc")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &RawBodyInput{
+		Signature: "sha256=abc",
+		Payload:   []byte{0xde, 0xad, 0xbe, 0xef},
+	}, got)
+
+	// Encode.
+	req, err := co.NewRequest("POST", "/hooks", &RawBodyInput{Payload: []byte("hello")})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/octet-stream", req.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "hello", string(body))
+}
+
+func TestRawBody_Text(t *testing.T) {
+	co, err := New(TextBodyInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/logs", strings.NewReader("GET / 200\n"))
+	r.Header.Set("Content-Type", "text/plain")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "GET / 200\n", got.(*TextBodyInput).Log)
+
+	// Too large.
+	r, _ = http.NewRequest("POST", "/logs", strings.NewReader(strings.Repeat("x", 17)))
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrRequestTooLarge)
+
+	// Encode.
+	req, err := co.NewRequest("POST", "/logs", &TextBodyInput{Log: "GET / 200\n"})
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", req.Header.Get("Content-Type"))
+	body, _ := io.ReadAll(req.Body)
+	assert.Equal(t, "GET / 200\n", string(body))
+}
+
+func TestRawBody_Reader(t *testing.T) {
+	co, err := New(StreamBodyInput{})
+	assert.NoError(t, err)
+
+	// The body is streamed, i.e. not read while decoding.
+	src := &countingReader{Reader: strings.NewReader("PNG data")}
+	r, _ := http.NewRequest("POST", "/images", src)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, src.n)
+	body, err := io.ReadAll(got.(*StreamBodyInput).Payload)
+	assert.NoError(t, err)
+	assert.Equal(t, "PNG data", string(body))
+
+	// Encode, the Content-Type is set by the "type" directive.
+	req, err := co.NewRequest("POST", "/images", &StreamBodyInput{Payload: strings.NewReader("PNG data")})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", req.Header.Get("Content-Type"))
+	body, _ = io.ReadAll(req.Body)
+	assert.Equal(t, "PNG data", string(body))
+
+	// A nil reader leaves the body unset.
+	req, err = co.NewRequest("POST", "/images", &StreamBodyInput{})
+	assert.NoError(t, err)
+	assert.Nil(t, req.Body)
+	assert.Empty(t, req.Header.Get("Content-Type"))
+}
+
+func TestRawBody_ReadCloser(t *testing.T) {
+	co, err := New(StreamCloserBodyInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/uploads", strings.NewReader("0123456789"))
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	payload := got.(*StreamCloserBodyInput).Payload
+	_, err = io.ReadAll(payload)
+	var maxBytesErr *http.MaxBytesError
+	assert.ErrorAs(t, err, &maxBytesErr)
+	assert.NoError(t, payload.Close())
+}
+
+func TestRawBody_TypeOverridesSerializerMediaType(t *testing.T) {
+	type ProblemInput struct {
+		Problem map[string]any `in:"body=json;type=application/problem+json"`
+	}
+	co, err := New(ProblemInput{})
+	assert.NoError(t, err)
+	req, err := co.NewRequest("POST", "/problems", &ProblemInput{Problem: map[string]any{"title": "oops"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/problem+json", req.Header.Get("Content-Type"))
+}
+
+// kindDirective is a user defined "type" directive, which sets the field to
+// its argument.
+type kindDirective struct{}
+
+func (*kindDirective) Decode(rtm *DirectiveRuntime) error {
+	rtm.Value.Elem().SetString(rtm.Directive.Argv[0])
+	return nil
+}
+
+func (*kindDirective) Encode(*DirectiveRuntime) error { return nil }
+
+func TestRawBody_TypeIsNotReserved(t *testing.T) {
+	RegisterDirective("type", &kindDirective{}, true)
+
+	type UploadInput struct {
+		Kind    string `in:"type=image"`
+		Payload []byte `in:"body=raw;type=image/png"`
+	}
+	co, err := New(UploadInput{})
+	assert.NoError(t, err)
+
+	req, err := co.NewRequest("POST", "/uploads", &UploadInput{Payload: []byte("PNG data")})
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", req.Header.Get("Content-Type"))
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, &UploadInput{Kind: "image", Payload: []byte("PNG data")}, got)
+}
+
+func TestRawBody_Invalid(t *testing.T) {
+	for _, c := range []struct {
+		input any
+		err   string
+	}{
+		{struct {
+			Payload []byte `in:"body=json,raw"`
+		}{}, `format "raw" cannot be combined with other formats`},
+		{struct {
+			Payload int `in:"body=text"`
+		}{}, "type mismatch"},
+		{struct {
+			Payload map[string]any `in:"body=raw"`
+		}{}, "type mismatch"},
+		{struct {
+			Payload []byte `in:"body=raw;type=image/png,image/jpeg"`
+		}{}, "directive type: expect exactly one argument"},
+		{struct {
+			Payload []byte `in:"body=raw;type=/png"`
+		}{}, `directive type: invalid media type "/png"`},
+		{struct {
+			Payload []byte `in:"query=payload;maxbytes=8"`
+		}{}, "require the body directive"},
+	} {
+		_, err := New(c.input)
+		assert.ErrorContains(t, err, c.err)
+	}
+
+	for _, format := range []string{"raw", "text", "auto"} {
+		assert.PanicsWithError(t, "httpin: reserved body format: \""+format+"\"", func() {
+			RegisterBodyFormat(format, &tomlBody{})
+		})
+	}
+}
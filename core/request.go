@@ -0,0 +1,112 @@
+// directive: "request"
+// https://ggicci.github.io/httpin/directives/request
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/ggicci/owl"
+)
+
+// requestAttributes are the attributes of an HTTP request which can be
+// extracted by the "request" directive.
+var requestAttributes = map[string]func(*http.Request) string{
+	"method":      func(r *http.Request) string { return r.Method },
+	"host":        func(r *http.Request) string { return r.Host },
+	"scheme":      requestScheme,
+	"remote_addr": func(r *http.Request) string { return r.RemoteAddr },
+	"url":         requestURL,
+	"proto":       func(r *http.Request) string { return r.Proto },
+}
+
+// DirectiveRequest implements the "request" executor who extracts the metadata
+// of an HTTP request, e.g. method, host, scheme, remote_addr, url and proto.
+type DirectiveRequest struct{}
+
+func (*DirectiveRequest) Decode(rtm *DirectiveRuntime) error {
+	req := rtm.GetRequest()
+	values := make(map[string][]string)
+	for _, attr := range rtm.Directive.Argv {
+		if get := requestAttributes[attr]; get != nil {
+			if value := get(req); value != "" {
+				values[attr] = []string{value}
+			}
+		}
+	}
+	extractor := &FormExtractor{
+		Runtime: rtm,
+		Form: multipart.Form{
+			Value: values,
+		},
+	}
+	return extractor.Extract()
+}
+
+// Encode sets the method and the host of the HTTP request. Other attributes
+// are ignored, since they are determined by the HTTP client.
+func (*DirectiveRequest) Encode(rtm *DirectiveRuntime) error {
+	rb := rtm.GetRequestBuilder()
+	var setter func(string)
+	switch rtm.Directive.Argv[0] {
+	case "method":
+		setter = rb.SetMethod
+	case "host":
+		setter = rb.SetHost
+	default:
+		return nil
+	}
+	encoder := &FormEncoder{
+		Setter: func(_ string, value []string) {
+			if len(value) > 0 {
+				setter(value[0])
+			}
+		},
+	}
+	return encoder.Execute(rtm)
+}
+
+func requestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return r.URL.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// requestURL returns the absolute URL of the request. On the server side,
+// r.URL only contains the path and the querystring in most cases, the scheme
+// and the host are completed from the request.
+func requestURL(r *http.Request) string {
+	u := *r.URL
+	if u.Host == "" {
+		u.Host = r.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = requestScheme(r)
+	}
+	return u.String()
+}
+
+// validateRequestDirective ensures that the attributes referenced by the
+// "request" directive are supported.
+func validateRequestDirective(r *owl.Resolver) error {
+	d := r.GetDirective("request")
+	if d == nil {
+		return nil
+	}
+	if len(d.Argv) == 0 {
+		return errors.New("directive request: missing attribute name")
+	}
+	for _, attr := range d.Argv {
+		if requestAttributes[attr] == nil {
+			return fmt.Errorf("directive request: unknown attribute %q", attr)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectiveRequest_Decode(t *testing.T) {
+	type Input struct {
+		Method     string         `in:"request=method"`
+		Host       string         `in:"request=host"`
+		Scheme     string         `in:"request=scheme"`
+		RemoteAddr netip.AddrPort `in:"request=remote_addr"`
+		URL        *url.URL       `in:"request=url"`
+		Proto      string         `in:"request=proto"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("POST", "/users?page=1", nil)
+	r.Host = "api.example.com"
+	r.RemoteAddr = "192.0.2.1:1234"
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Input{
+		Method:     "POST",
+		Host:       "api.example.com",
+		Scheme:     "http",
+		RemoteAddr: netip.MustParseAddrPort("192.0.2.1:1234"),
+		URL: &url.URL{
+			Scheme:   "http",
+			Host:     "api.example.com",
+			Path:     "/users",
+			RawQuery: "page=1",
+		},
+		Proto: "HTTP/1.1",
+	}, got)
+
+	// TLS
+	r.TLS = &tls.ConnectionState{}
+	got, err = co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "https", got.(*Input).Scheme)
+	assert.Equal(t, "https://api.example.com/users?page=1", got.(*Input).URL.String())
+}
+
+func TestDirectiveRequest_Decode_InvalidValue(t *testing.T) {
+	type Input struct {
+		RemoteAddr netip.AddrPort `in:"request=remote_addr"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "@"
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "request", invalidField.Directive)
+	assert.Equal(t, "remote_addr", invalidField.Key)
+	assert.Equal(t, []string{"@"}, invalidField.Value)
+}
+
+func TestDirectiveRequest_New_Errors(t *testing.T) {
+	type InputUnknownAttribute struct {
+		Port int `in:"request=port"`
+	}
+	_, err := New(InputUnknownAttribute{})
+	assert.ErrorContains(t, err, "directive request: unknown attribute \"port\"")
+
+	type InputMissingAttribute struct {
+		Method string `in:"request"`
+	}
+	_, err = New(InputMissingAttribute{})
+	assert.ErrorContains(t, err, "directive request: missing attribute name")
+}
+
+func TestDirectiveRequest_NewRequest(t *testing.T) {
+	type Input struct {
+		Method string `in:"request=method"`
+		Host   string `in:"request=host;omitempty"`
+		Scheme string `in:"request=scheme"` // ignored
+		Page   int    `in:"query=page"`
+	}
+
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	req, err := co.NewRequest("GET", "https://example.com/users", &Input{
+		Method: "DELETE",
+		Host:   "api.example.com",
+		Scheme: "ftp",
+		Page:   1,
+	})
+	assert.NoError(t, err)
+
+	expected, _ := http.NewRequest("DELETE", "https://example.com/users?page=1", nil)
+	expected.Host = "api.example.com"
+	assert.Equal(t, expected, req)
+
+	// Empty host is omitted.
+	req, err = co.NewRequest("GET", "https://example.com/users", &Input{Method: "PUT"})
+	assert.NoError(t, err)
+	assert.Equal(t, "PUT", req.Method)
+	assert.Equal(t, "example.com", req.Host)
+}
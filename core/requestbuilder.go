@@ -1,10 +1,13 @@
 package core
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -13,6 +16,8 @@ import (
 )
 
 type RequestBuilder struct {
+	Method     string // overrides the method of the request if not empty
+	Host       string // overrides the host of the request if not empty
 	Query      url.Values
 	Form       url.Values
 	Attachment map[string][]FileMarshaler
@@ -22,6 +27,10 @@ type RequestBuilder struct {
 	BodyType   string            // json, xml, etc.
 	Body       io.ReadCloser
 	ctx        context.Context
+	ctxChanged bool // values were added to ctx by SetContextValue
+
+	jsonDocument any    // built by SetJSONPointer
+	contentType  string // overrides the media type of BodyType if not empty
 }
 
 func NewRequestBuilder(ctx context.Context) *RequestBuilder {
@@ -41,6 +50,14 @@ func (rb *RequestBuilder) Populate(req *http.Request) error {
 		return err
 	}
 
+	// Populate the method and the host.
+	if rb.Method != "" {
+		req.Method = rb.Method
+	}
+	if rb.Host != "" {
+		req.Host = rb.Host
+	}
+
 	// Populate the querystring.
 	req.URL.RawQuery = rb.Query.Encode()
 
@@ -56,6 +73,13 @@ func (rb *RequestBuilder) Populate(req *http.Request) error {
 	}
 
 	// Populate body.
+	if rb.jsonDocument != nil {
+		body, err := json.Marshal(rb.jsonDocument)
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON body: %w", err)
+		}
+		rb.SetBody("json", io.NopCloser(bytes.NewReader(body)))
+	}
 	if rb.hasBody() {
 		req.Body = rb.Body
 		rb.Header.Set("Content-Type", rb.bodyContentType())
@@ -81,9 +105,22 @@ func (rb *RequestBuilder) Populate(req *http.Request) error {
 		req.AddCookie(cookie)
 	}
 
+	// Populate the context values.
+	if rb.ctxChanged {
+		*req = *req.WithContext(rb.ctx)
+	}
+
 	return nil
 }
 
+func (rb *RequestBuilder) SetMethod(method string) {
+	rb.Method = method
+}
+
+func (rb *RequestBuilder) SetHost(host string) {
+	rb.Host = host
+}
+
 func (rb *RequestBuilder) SetQuery(key string, value []string) {
 	rb.Query[key] = value
 }
@@ -96,6 +133,27 @@ func (rb *RequestBuilder) SetHeader(key string, value []string) {
 	rb.Header[http.CanonicalHeaderKey(key)] = value
 }
 
+// SetCookie replaces the cookies of the given name with the given values. One
+// cookie is added for each value.
+func (rb *RequestBuilder) SetCookie(key string, value []string) {
+	cookies := rb.Cookie[:0]
+	for _, cookie := range rb.Cookie {
+		if cookie.Name != key {
+			cookies = append(cookies, cookie)
+		}
+	}
+	for _, v := range value {
+		cookies = append(cookies, &http.Cookie{Name: key, Value: v})
+	}
+	rb.Cookie = cookies
+}
+
+// SetContextValue adds a value to the context of the request being built.
+func (rb *RequestBuilder) SetContextValue(key, value any) {
+	rb.ctx = context.WithValue(rb.ctx, key, value)
+	rb.ctxChanged = true
+}
+
 func (rb *RequestBuilder) SetPath(key string, value []string) {
 	if len(value) > 0 {
 		rb.Path[key] = value[0]
@@ -107,24 +165,51 @@ func (rb *RequestBuilder) SetBody(bodyType string, bodyReader io.ReadCloser) {
 	rb.Body = bodyReader
 }
 
+// SetBodyStream sets the body to the elements of the sequence, which are
+// encoded lazily while the body is being read. bodyType is "ndjson" for
+// newline-delimited JSON, or "json" for a JSON array. An error yielded by the
+// sequence, or the cancellation of the context, aborts the body.
+func (rb *RequestBuilder) SetBodyStream(bodyType string, elems iter.Seq2[any, error]) {
+	rb.SetBody(bodyType, newStreamBody(rb.ctx, bodyType, elems))
+}
+
+// SetJSONPointer sets the value in the JSON document at the location referenced
+// by the JSON pointer (RFC 6901). The missing objects along the path are
+// created. The document will be used as the JSON body of the request.
+func (rb *RequestBuilder) SetJSONPointer(pointer string, value any) error {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return err
+	}
+	document, err := setJSONPointer(rb.jsonDocument, tokens, value)
+	if err != nil {
+		return fmt.Errorf("cannot set JSON pointer %q: %w", pointer, err)
+	}
+	rb.jsonDocument = document
+	return nil
+}
+
 func (rb *RequestBuilder) SetAttachment(key string, files []FileMarshaler) {
 	rb.Attachment[key] = files
 }
 
 func (rb *RequestBuilder) bodyContentType() string {
-	switch rb.BodyType {
-	case "json":
-		return "application/json"
-	case "xml":
-		return "application/xml"
+	if rb.BodyType == "" {
+		return ""
+	}
+	if rb.contentType != "" {
+		return rb.contentType
 	}
-	return ""
+	return getBodyMediaTypes(rb.BodyType)[0]
 }
 
 func (rb *RequestBuilder) validate() error {
-	if rb.hasForm() && rb.hasBody() {
+	if rb.hasForm() && (rb.hasBody() || rb.jsonDocument != nil) {
 		return errors.New("cannot use both form and body directive at the same time")
 	}
+	if rb.hasBody() && rb.jsonDocument != nil {
+		return errors.New("cannot use both jsonptr and body directive at the same time")
+	}
 	return nil
 }
 
@@ -51,3 +51,15 @@ func TestIssue88_CancelStreaming(t *testing.T) {
 	err := req.ParseMultipartForm(32 << 20)
 	assert.ErrorContains(t, err, "context canceled")
 }
+
+func TestRequestBuilder_SetCookie(t *testing.T) {
+	rb := NewRequestBuilder(context.Background())
+	rb.SetCookie("a", []string{"1"})
+	rb.SetCookie("b", []string{"2", "3"})
+	rb.SetCookie("a", []string{"4"})
+	assert.Equal(t, []*http.Cookie{
+		{Name: "b", Value: "2"},
+		{Name: "b", Value: "3"},
+		{Name: "a", Value: "4"},
+	}, rb.Cookie)
+}
@@ -0,0 +1,317 @@
+package sfv
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parser implements the parsing algorithms of RFC 8941, Section 4.2.
+type parser struct {
+	s   string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidSyntax, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.s) }
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *parser) discardSP() {
+	for !p.eof() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *parser) discardOWS() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// end discards the trailing spaces and ensures that the input is consumed.
+func (p *parser) end() error {
+	p.discardSP()
+	if !p.eof() {
+		return p.errorf("unexpected character %q", p.peek())
+	}
+	return nil
+}
+
+func (p *parser) parseList() (List, error) {
+	var list List
+	for !p.eof() {
+		m, err := p.parseMember()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, m)
+		p.discardOWS()
+		if p.eof() {
+			return list, nil
+		}
+		if p.peek() != ',' {
+			return nil, p.errorf("expected \",\" but got %q", p.peek())
+		}
+		p.pos++
+		p.discardOWS()
+		if p.eof() {
+			return nil, p.errorf("trailing comma")
+		}
+	}
+	return list, nil
+}
+
+func (p *parser) parseDictionary() (Dictionary, error) {
+	var dict Dictionary
+	for !p.eof() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var m Member
+		if p.peek() == '=' {
+			p.pos++
+			if m, err = p.parseMember(); err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.parseParams()
+			if err != nil {
+				return nil, err
+			}
+			m = Item{Value: true, Params: params}
+		}
+		dict.Set(key, m)
+		p.discardOWS()
+		if p.eof() {
+			return dict, nil
+		}
+		if p.peek() != ',' {
+			return nil, p.errorf("expected \",\" but got %q", p.peek())
+		}
+		p.pos++
+		p.discardOWS()
+		if p.eof() {
+			return nil, p.errorf("trailing comma")
+		}
+	}
+	return dict, nil
+}
+
+func (p *parser) parseMember() (Member, error) {
+	if p.peek() == '(' {
+		return p.parseInnerList()
+	}
+	return p.parseItem()
+}
+
+func (p *parser) parseInnerList() (InnerList, error) {
+	p.pos++ // consume "("
+	var items []Item
+	for !p.eof() {
+		p.discardSP()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+			if err != nil {
+				return InnerList{}, err
+			}
+			return InnerList{Items: items, Params: params}, nil
+		}
+		item, err := p.parseItem()
+		if err != nil {
+			return InnerList{}, err
+		}
+		items = append(items, item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return InnerList{}, p.errorf("expected \" \" or \")\" but got %q", c)
+		}
+	}
+	return InnerList{}, p.errorf("unterminated inner list")
+}
+
+func (p *parser) parseItem() (Item, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.parseParams()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: value, Params: params}, nil
+}
+
+func (p *parser) parseParams() (Params, error) {
+	var params Params
+	for p.peek() == ';' {
+		p.pos++
+		p.discardSP()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value BareItem = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.parseBareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params.Set(key, value)
+	}
+	return params, nil
+}
+
+func (p *parser) parseKey() (string, error) {
+	c := p.peek()
+	if !isLCAlpha(c) && c != '*' {
+		return "", p.errorf("invalid key")
+	}
+	start := p.pos
+	for !p.eof() && isKeyChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *parser) parseBareItem() (BareItem, error) {
+	c := p.peek()
+	switch {
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case c == '*' || isAlpha(c):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		return p.parseBoolean()
+	default:
+		return nil, p.errorf("invalid bare item")
+	}
+}
+
+func (p *parser) parseNumber() (BareItem, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("invalid number")
+	}
+	isDecimal := false
+	digits := 0
+	for !p.eof() {
+		c := p.s[p.pos]
+		if isDigit(c) {
+			digits++
+		} else if c == '.' && !isDecimal {
+			if digits > 12 {
+				return nil, p.errorf("decimal too long")
+			}
+			isDecimal = true
+			digits = 0
+		} else {
+			break
+		}
+		p.pos++
+		if !isDecimal && digits > 15 {
+			return nil, p.errorf("integer too long")
+		}
+	}
+
+	literal := p.s[start:p.pos]
+	if !isDecimal {
+		v, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", literal)
+		}
+		return v, nil
+	}
+	if digits == 0 || digits > 3 {
+		return nil, p.errorf("invalid decimal %q", literal)
+	}
+	v, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, p.errorf("invalid decimal %q", literal)
+	}
+	return v, nil
+}
+
+func (p *parser) parseString() (string, error) {
+	p.pos++ // consume DQUOTE
+	var b strings.Builder
+	for !p.eof() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated escape")
+			}
+			next := p.s[p.pos]
+			if next != '"' && next != '\\' {
+				return "", p.errorf("invalid escape %q", next)
+			}
+			b.WriteByte(next)
+			p.pos++
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) parseToken() Token {
+	start := p.pos
+	p.pos++ // the first character has been checked
+	for !p.eof() && isTokenChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return Token(p.s[start:p.pos])
+}
+
+func (p *parser) parseByteSequence() ([]byte, error) {
+	p.pos++ // consume ":"
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end == -1 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	encoded := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	v, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, p.errorf("invalid byte sequence")
+	}
+	return v, nil
+}
+
+func (p *parser) parseBoolean() (bool, error) {
+	p.pos++ // consume "?"
+	switch p.peek() {
+	case '1':
+		p.pos++
+		return true, nil
+	case '0':
+		p.pos++
+		return false, nil
+	default:
+		return false, p.errorf("invalid boolean")
+	}
+}
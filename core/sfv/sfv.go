@@ -0,0 +1,401 @@
+// Package sfv implements Structured Field Values for HTTP, see RFC 8941.
+//
+// The types Item, List and Dictionary can be used as the fields of an input
+// struct directly, e.g.
+//
+//	type Input struct {
+//	    Priority sfv.Dictionary `in:"header=Priority"`
+//	}
+//
+// They implement the StringSlicable interface of httpin. Multiple field lines
+// of the same header are combined with commas before parsing, as RFC 8941
+// requires.
+package sfv
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidSyntax = errors.New("invalid structured field value")
+	ErrInvalidValue  = errors.New("invalid value for structured field")
+)
+
+// BareItem is the value of an Item or a parameter. It is one of the types:
+//
+//   - int64: Integer
+//   - float64: Decimal
+//   - string: String
+//   - Token: Token
+//   - []byte: Byte Sequence
+//   - bool: Boolean
+type BareItem any
+
+// Token is a short textual word, e.g. the "gzip" in Accept-Encoding.
+type Token string
+
+// Param is a key-value pair of the parameters.
+type Param struct {
+	Key   string
+	Value BareItem
+}
+
+// Params is an ordered map of parameters.
+type Params []Param
+
+// Get returns the value of the parameter by key.
+func (ps Params) Get(key string) (BareItem, bool) {
+	for _, p := range ps {
+		if p.Key == key {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the value of the parameter. The order is kept if the key exists.
+func (ps *Params) Set(key string, value BareItem) {
+	for i := range *ps {
+		if (*ps)[i].Key == key {
+			(*ps)[i].Value = value
+			return
+		}
+	}
+	*ps = append(*ps, Param{key, value})
+}
+
+// Member is a member of a List or a Dictionary, which is either an Item or an
+// InnerList.
+type Member interface {
+	member()
+}
+
+// Item is a bare item with parameters.
+type Item struct {
+	Value  BareItem
+	Params Params
+}
+
+// InnerList is a list of items with parameters.
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+func (Item) member()      {}
+func (InnerList) member() {}
+
+// List is an ordered list of members.
+type List []Member
+
+// DictMember is a key-value pair of a Dictionary.
+type DictMember struct {
+	Key   string
+	Value Member
+}
+
+// Dictionary is an ordered map of members.
+type Dictionary []DictMember
+
+// Get returns the member by key.
+func (d Dictionary) Get(key string) (Member, bool) {
+	for _, m := range d {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set sets the member. The order is kept if the key exists.
+func (d *Dictionary) Set(key string, value Member) {
+	for i := range *d {
+		if (*d)[i].Key == key {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, DictMember{key, value})
+}
+
+func (item Item) ToString() (string, error) {
+	var b strings.Builder
+	if err := serializeItem(&b, item); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (item *Item) FromString(s string) error {
+	p := &parser{s: s}
+	p.discardSP()
+	v, err := p.parseItem()
+	if err != nil {
+		return err
+	}
+	if err := p.end(); err != nil {
+		return err
+	}
+	*item = v
+	return nil
+}
+
+func (item Item) ToStringSlice() ([]string, error) {
+	s, err := item.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func (item *Item) FromStringSlice(values []string) error {
+	return item.FromString(strings.Join(values, ","))
+}
+
+func (l List) ToString() (string, error) {
+	var b strings.Builder
+	for i, m := range l {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := serializeMember(&b, m); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (l *List) FromString(s string) error {
+	p := &parser{s: s}
+	p.discardSP()
+	v, err := p.parseList()
+	if err != nil {
+		return err
+	}
+	if err := p.end(); err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+func (l List) ToStringSlice() ([]string, error) {
+	if len(l) == 0 {
+		return []string{}, nil // an empty list is not serialized
+	}
+	s, err := l.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func (l *List) FromStringSlice(values []string) error {
+	return l.FromString(strings.Join(values, ","))
+}
+
+func (d Dictionary) ToString() (string, error) {
+	var b strings.Builder
+	for i, m := range d {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := serializeKey(&b, m.Key); err != nil {
+			return "", err
+		}
+		if item, ok := m.Value.(Item); ok && item.Value == true {
+			if err := serializeParams(&b, item.Params); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte('=')
+		if err := serializeMember(&b, m.Value); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (d *Dictionary) FromString(s string) error {
+	p := &parser{s: s}
+	p.discardSP()
+	v, err := p.parseDictionary()
+	if err != nil {
+		return err
+	}
+	if err := p.end(); err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+func (d Dictionary) ToStringSlice() ([]string, error) {
+	if len(d) == 0 {
+		return []string{}, nil // an empty dictionary is not serialized
+	}
+	s, err := d.ToString()
+	if err != nil {
+		return nil, err
+	}
+	return []string{s}, nil
+}
+
+func (d *Dictionary) FromStringSlice(values []string) error {
+	return d.FromString(strings.Join(values, ","))
+}
+
+func serializeMember(b *strings.Builder, m Member) error {
+	switch v := m.(type) {
+	case Item:
+		return serializeItem(b, v)
+	case InnerList:
+		return serializeInnerList(b, v)
+	default:
+		return fmt.Errorf("%w: unknown member type %T", ErrInvalidValue, m)
+	}
+}
+
+func serializeInnerList(b *strings.Builder, l InnerList) error {
+	b.WriteByte('(')
+	for i, item := range l.Items {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if err := serializeItem(b, item); err != nil {
+			return err
+		}
+	}
+	b.WriteByte(')')
+	return serializeParams(b, l.Params)
+}
+
+func serializeItem(b *strings.Builder, item Item) error {
+	if err := serializeBareItem(b, item.Value); err != nil {
+		return err
+	}
+	return serializeParams(b, item.Params)
+}
+
+func serializeParams(b *strings.Builder, params Params) error {
+	for _, p := range params {
+		b.WriteByte(';')
+		if err := serializeKey(b, p.Key); err != nil {
+			return err
+		}
+		if p.Value == true {
+			continue
+		}
+		b.WriteByte('=')
+		if err := serializeBareItem(b, p.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serializeKey(b *strings.Builder, key string) error {
+	if !isValidKey(key) {
+		return fmt.Errorf("%w: invalid key %q", ErrInvalidValue, key)
+	}
+	b.WriteString(key)
+	return nil
+}
+
+const maxInteger = 999_999_999_999_999
+
+func serializeBareItem(b *strings.Builder, value BareItem) error {
+	switch v := value.(type) {
+	case int:
+		return serializeBareItem(b, int64(v))
+	case int64:
+		if v > maxInteger || v < -maxInteger {
+			return fmt.Errorf("%w: integer %d out of range", ErrInvalidValue, v)
+		}
+		b.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		v = math.RoundToEven(v*1000) / 1000
+		if math.IsNaN(v) || math.IsInf(v, 0) || math.Abs(v) >= 1e12 {
+			return fmt.Errorf("%w: decimal %v out of range", ErrInvalidValue, v)
+		}
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		b.WriteString(s)
+	case string:
+		b.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < 0x20 || c > 0x7e {
+				return fmt.Errorf("%w: invalid character in string %q", ErrInvalidValue, v)
+			}
+			if c == '"' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte('"')
+	case Token:
+		if !isValidToken(string(v)) {
+			return fmt.Errorf("%w: invalid token %q", ErrInvalidValue, v)
+		}
+		b.WriteString(string(v))
+	case []byte:
+		b.WriteByte(':')
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+		b.WriteByte(':')
+	case bool:
+		if v {
+			b.WriteString("?1")
+		} else {
+			b.WriteString("?0")
+		}
+	default:
+		return fmt.Errorf("%w: unsupported bare item type %T", ErrInvalidValue, value)
+	}
+	return nil
+}
+
+func isValidKey(key string) bool {
+	if key == "" || !(isLCAlpha(key[0]) || key[0] == '*') {
+		return false
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidToken(token string) bool {
+	if token == "" || !(isAlpha(token[0]) || token[0] == '*') {
+		return false
+	}
+	for i := 1; i < len(token); i++ {
+		if !isTokenChar(token[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLCAlpha(c byte) bool { return c >= 'a' && c <= 'z' }
+func isAlpha(c byte) bool   { return isLCAlpha(c) || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool   { return c >= '0' && c <= '9' }
+
+func isKeyChar(c byte) bool {
+	return isLCAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+func isTokenChar(c byte) bool {
+	// tchar, see RFC 9110 Section 5.6.2, plus ":" and "/".
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~:/", c) >= 0
+}
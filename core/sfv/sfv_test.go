@@ -0,0 +1,141 @@
+package sfv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItem(t *testing.T) {
+	for _, c := range []struct {
+		input      string
+		expected   Item
+		serialized string
+	}{
+		{"42", Item{Value: int64(42)}, "42"},
+		{"-42", Item{Value: int64(-42)}, "-42"},
+		{"4.5", Item{Value: 4.5}, "4.5"},
+		{"-0.125", Item{Value: -0.125}, "-0.125"},
+		{`"hello \"world\""`, Item{Value: `hello "world"`}, `"hello \"world\""`},
+		{"foo123/456", Item{Value: Token("foo123/456")}, "foo123/456"},
+		{":aGVsbG8=:", Item{Value: []byte("hello")}, ":aGVsbG8=:"},
+		{"?1", Item{Value: true}, "?1"},
+		{"?0", Item{Value: false}, "?0"},
+		{"  text/html;q=0.5; level=1 ", Item{
+			Value:  Token("text/html"),
+			Params: Params{{"q", 0.5}, {"level", int64(1)}},
+		}, "text/html;q=0.5;level=1"},
+		{"abc;a;b=?0", Item{
+			Value:  Token("abc"),
+			Params: Params{{"a", true}, {"b", false}},
+		}, "abc;a;b=?0"},
+	} {
+		var item Item
+		assert.NoError(t, item.FromString(c.input), c.input)
+		assert.Equal(t, c.expected, item, c.input)
+		serialized, err := item.ToString()
+		assert.NoError(t, err)
+		assert.Equal(t, c.serialized, serialized)
+	}
+}
+
+func TestItem_InvalidSyntax(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"1234567890123456", // integer too long
+		"1234567890123.0",  // decimal too long
+		"1.2345",           // too many fractional digits
+		"1.",               // no fractional digits
+		`"unterminated`,    // unterminated string
+		`"bad \n escape"`,  // invalid escape
+		":aGVsbG8=",        // unterminated byte sequence
+		"?2",               // invalid boolean
+		"a, b",             // not an item
+		"abc;A=1",          // invalid key
+		"é",                // invalid bare item
+		"\"\x7f\"",         // invalid character in string
+		":not base64!:",    // invalid byte sequence
+	} {
+		var item Item
+		assert.ErrorIs(t, item.FromString(input), ErrInvalidSyntax, input)
+	}
+}
+
+func TestItem_InvalidValue(t *testing.T) {
+	for _, item := range []Item{
+		{Value: int64(1_000_000_000_000_000)},
+		{Value: 1e12},
+		{Value: "é"},
+		{Value: Token("1abc")},
+		{Value: struct{}{}},
+		{Value: int64(1), Params: Params{{"Key", true}}},
+	} {
+		_, err := item.ToString()
+		assert.ErrorIs(t, err, ErrInvalidValue)
+	}
+}
+
+func TestList(t *testing.T) {
+	var list List
+	assert.NoError(t, list.FromStringSlice([]string{`sugar, tea;fresh`, `(rum "cola");alcohol=?1, ()`}))
+	assert.Equal(t, List{
+		Item{Value: Token("sugar")},
+		Item{Value: Token("tea"), Params: Params{{"fresh", true}}},
+		InnerList{
+			Items:  []Item{{Value: Token("rum")}, {Value: "cola"}},
+			Params: Params{{"alcohol", true}},
+		},
+		InnerList{},
+	}, list)
+
+	values, err := list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`sugar, tea;fresh, (rum "cola");alcohol, ()`}, values)
+
+	assert.NoError(t, list.FromString(""))
+	assert.Empty(t, list)
+	values, err = list.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+
+	assert.ErrorIs(t, list.FromString("a,"), ErrInvalidSyntax)
+	assert.ErrorIs(t, list.FromString("a b"), ErrInvalidSyntax)
+	assert.ErrorIs(t, list.FromString("(a b"), ErrInvalidSyntax)
+	assert.ErrorIs(t, list.FromString("(a,b)"), ErrInvalidSyntax)
+}
+
+func TestDictionary(t *testing.T) {
+	var dict Dictionary
+	assert.NoError(t, dict.FromStringSlice([]string{"u=1, i", "a=(1 2), b;x=?0, u=3"}))
+	assert.Equal(t, Dictionary{
+		{"u", Item{Value: int64(3)}},
+		{"i", Item{Value: true}},
+		{"a", InnerList{Items: []Item{{Value: int64(1)}, {Value: int64(2)}}}},
+		{"b", Item{Value: true, Params: Params{{"x", false}}}},
+	}, dict)
+
+	u, ok := dict.Get("u")
+	assert.True(t, ok)
+	assert.Equal(t, Item{Value: int64(3)}, u)
+	_, ok = dict.Get("missing")
+	assert.False(t, ok)
+
+	values, err := dict.ToStringSlice()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u=3, i, a=(1 2), b;x=?0"}, values)
+
+	assert.ErrorIs(t, dict.FromString("U=1"), ErrInvalidSyntax)
+	assert.ErrorIs(t, dict.FromString("a=1,"), ErrInvalidSyntax)
+	assert.ErrorIs(t, dict.FromString("a=1;"), ErrInvalidSyntax)
+}
+
+func TestParams_Set(t *testing.T) {
+	var params Params
+	params.Set("a", int64(1))
+	params.Set("b", true)
+	params.Set("a", int64(2))
+	assert.Equal(t, Params{{"a", int64(2)}, {"b", true}}, params)
+	v, ok := params.Get("b")
+	assert.True(t, ok)
+	assert.Equal(t, true, v)
+}
@@ -0,0 +1,467 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ggicci/httpin/internal"
+	"github.com/ggicci/owl"
+)
+
+// bodyFormatNDJSON is the body format of newline-delimited JSON, i.e. one JSON
+// value per line, which is decoded lazily. For example:
+//
+//	type IngestInput struct {
+//		Events iter.Seq2[*Event, error] `in:"body=ndjson"`
+//	}
+//
+//	for event, err := range input.Events {
+//		if err != nil { ... }
+//		...
+//	}
+//
+// The field type must be a stream type, i.e. iter.Seq2[T, error], Stream[T] or
+// *Stream[T], or a slice, which reads the whole body. A stream type field with
+// the "json" body format streams the elements of a top-level JSON array
+// likewise. The elements are decoded by encoding/json as the stream is
+// consumed, and the "strict", "usenumber" and "maxbytes" directives apply.
+// A stream can be consumed only once, and an error stops it.
+//
+// When encoding, iter.Seq[T] is accepted as well, the elements are encoded as
+// the request body is being read, see RequestBuilder.SetBodyStream. The
+// Content-Type defaults to "application/x-ndjson".
+const bodyFormatNDJSON = "ndjson"
+
+const ndjsonContentType = "application/x-ndjson"
+
+// Stream is a stream of the elements of a streaming body, see
+// bodyFormatNDJSON. The elements are decoded lazily while iterating:
+//
+//	for input.Events.Next() {
+//		event := input.Events.Value()
+//		...
+//	}
+//	if err := input.Events.Err(); err != nil { ... }
+//
+// Or by a range-over-func loop over All.
+type Stream[T any] struct {
+	decoder elementDecoder
+	value   T
+	err     error
+}
+
+// Next decodes the next element, which is then available through Value. It
+// returns false when the stream ends or an error occurs, see Err.
+func (s *Stream[T]) Next() bool {
+	if s.decoder == nil || s.err != nil {
+		return false
+	}
+	var value T
+	if err := s.decoder.Decode(&value); err != nil {
+		if err == io.EOF {
+			s.decoder = nil
+		} else {
+			s.err = err
+		}
+		return false
+	}
+	s.value = value
+	return true
+}
+
+// Value returns the element decoded by the last call to Next.
+func (s *Stream[T]) Value() T {
+	return s.value
+}
+
+// Err returns the error that stopped the stream, nil if the stream ended
+// normally or hasn't ended.
+func (s *Stream[T]) Err() error {
+	return s.err
+}
+
+// All returns an iterator over the remaining elements. The error that stops
+// the stream is yielded with a zero element.
+func (s *Stream[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for s.Next() {
+			if !yield(s.value, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			var zero T
+			yield(zero, s.err)
+		}
+	}
+}
+
+func (s *Stream[T]) bindStream(decoder elementDecoder) {
+	*s = Stream[T]{decoder: decoder}
+}
+
+func (s *Stream[T]) elements() iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for value, err := range s.All() {
+			if !yield(value, err) {
+				return
+			}
+		}
+	}
+}
+
+// streamType is implemented by *Stream[T].
+type streamType interface {
+	bindStream(elementDecoder)
+	elements() iter.Seq2[any, error]
+}
+
+// elementDecoder decodes the elements of a streaming body one by one. io.EOF
+// is returned after the last element. The errors are sticky.
+type elementDecoder interface {
+	Decode(dst any) error
+}
+
+var (
+	streamTypeType = internal.TypeOf[streamType]()
+	errorType      = internal.TypeOf[error]()
+)
+
+// seqKind tells whether typ is an iter.Seq[T] (1) or iter.Seq2[T, error] (2),
+// returns 0 if neither.
+func seqKind(typ reflect.Type) int {
+	if typ.Kind() != reflect.Func || typ.NumIn() != 1 || typ.NumOut() != 0 {
+		return 0
+	}
+	yield := typ.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return 0
+	}
+	switch {
+	case yield.NumIn() == 1:
+		return 1
+	case yield.NumIn() == 2 && yield.In(1) == errorType:
+		return 2
+	}
+	return 0
+}
+
+// isStreamType tells whether typ can be decoded from or encoded to a
+// streaming body.
+func isStreamType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		return typ.Implements(streamTypeType)
+	}
+	return reflect.PointerTo(typ).Implements(streamTypeType) || seqKind(typ) > 0
+}
+
+// validateStreamBodyDirective ensures that the "ndjson" body format and the
+// stream type fields are used with a single format, either "ndjson" or
+// "json", and the field type fits.
+func validateStreamBodyDirective(r *owl.Resolver) error {
+	d := r.GetDirective("body")
+	if d == nil {
+		return nil
+	}
+	formats := d.Argv
+	if len(formats) == 0 {
+		formats = []string{"json"}
+	}
+	isStream := isStreamType(r.Type)
+	isNDJSON := false
+	for _, format := range formats {
+		isNDJSON = isNDJSON || strings.EqualFold(format, bodyFormatNDJSON)
+	}
+	if !isStream && !isNDJSON {
+		return nil
+	}
+	if len(formats) > 1 || (!isNDJSON && !strings.EqualFold(formats[0], "json")) {
+		return fmt.Errorf("directive body: a stream requires exactly one format, ndjson or json, got %v", formats)
+	}
+	if !isStream && r.Type.Kind() != reflect.Slice {
+		return fmt.Errorf("directive body: %w: format %q requires type iter.Seq2[T, error], Stream[T] or a slice, got %q",
+			ErrTypeMismatch, bodyFormatNDJSON, r.Type)
+	}
+	return nil
+}
+
+func decodeStreamBody(rtm *DirectiveRuntime, format string) error {
+	body, err := rtm.getRequestBodyReader()
+	if err != nil {
+		return err
+	}
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	options := rtm.getBodyOptions()
+	body = options.limit(body)
+	jsonBody := options.configure(&JSONBody{}).(*JSONBody)
+
+	var decoder elementDecoder
+	if format == bodyFormatNDJSON {
+		decoder = &ndjsonDecoder{reader: bufio.NewReader(body), body: *jsonBody}
+	} else {
+		decoder = &jsonArrayDecoder{decoder: jsonBody.newDecoder(body), body: jsonBody}
+	}
+
+	field := rtm.Value.Elem()
+	typ := field.Type()
+	switch {
+	case typ.Kind() == reflect.Pointer:
+		stream := reflect.New(typ.Elem())
+		stream.Interface().(streamType).bindStream(decoder)
+		field.Set(stream)
+	case typ.Kind() == reflect.Struct:
+		field.Addr().Interface().(streamType).bindStream(decoder)
+	case typ.Kind() == reflect.Slice:
+		return decodeStreamToSlice(decoder, field)
+	case seqKind(typ) == 2:
+		field.Set(makeSeq2(typ, decoder))
+	default:
+		return fmt.Errorf("%w: %v can only be encoded", ErrUnsupportedType, typ)
+	}
+	return nil
+}
+
+func decodeStreamToSlice(decoder elementDecoder, field reflect.Value) error {
+	elems := reflect.MakeSlice(field.Type(), 0, 0)
+	for {
+		elem := reflect.New(field.Type().Elem())
+		if err := decoder.Decode(elem.Interface()); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		elems = reflect.Append(elems, elem.Elem())
+	}
+	field.Set(elems)
+	return nil
+}
+
+// makeSeq2 makes an iter.Seq2[T, error] of type typ, which yields the
+// elements decoded by decoder.
+func makeSeq2(typ reflect.Type, decoder elementDecoder) reflect.Value {
+	elemType := typ.In(0).In(0)
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		yield := args[0]
+		for {
+			elem := reflect.New(elemType)
+			err := decoder.Decode(elem.Interface())
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				yield.Call([]reflect.Value{reflect.Zero(elemType), reflect.ValueOf(&err).Elem()})
+				return nil
+			}
+			if !yield.Call([]reflect.Value{elem.Elem(), reflect.Zero(errorType)})[0].Bool() {
+				return nil
+			}
+		}
+	})
+}
+
+func encodeStreamBody(rtm *DirectiveRuntime, format string) error {
+	field := rtm.Value
+	var elems iter.Seq2[any, error]
+	switch {
+	case field.Kind() == reflect.Slice:
+		elems = sliceElements(field)
+	case seqKind(field.Type()) > 0:
+		if field.IsNil() {
+			return nil
+		}
+		elems = seqElements(field)
+	case field.Kind() == reflect.Pointer:
+		if field.IsNil() {
+			return nil
+		}
+		elems = field.Interface().(streamType).elements()
+	default:
+		stream := reflect.New(field.Type())
+		stream.Elem().Set(field)
+		elems = stream.Interface().(streamType).elements()
+	}
+
+	rb := rtm.GetRequestBuilder()
+	rb.SetBodyStream(format, elems)
+	if format == bodyFormatNDJSON {
+		rb.contentType = rtm.getBodyOptions().contentType(ndjsonContentType)
+	} else {
+		rb.contentType = rtm.getBodyOptions().contentType("")
+	}
+	rtm.MarkFieldSet(true)
+	return nil
+}
+
+func sliceElements(slice reflect.Value) iter.Seq2[any, error] {
+	return func(yield func(any, error) bool) {
+		for i := 0; i < slice.Len(); i++ {
+			if !yield(slice.Index(i).Interface(), nil) {
+				return
+			}
+		}
+	}
+}
+
+// seqElements converts an iter.Seq[T] or iter.Seq2[T, error] to an
+// iter.Seq2[any, error].
+func seqElements(seq reflect.Value) iter.Seq2[any, error] {
+	yieldType := seq.Type().In(0)
+	withError := seqKind(seq.Type()) == 2
+	return func(yield func(any, error) bool) {
+		seq.Call([]reflect.Value{reflect.MakeFunc(yieldType, func(args []reflect.Value) []reflect.Value {
+			var err error
+			if withError && !args[1].IsNil() {
+				err = args[1].Interface().(error)
+			}
+			next := yield(args[0].Interface(), err)
+			return []reflect.Value{reflect.ValueOf(next).Convert(yieldType.Out(0))}
+		})})
+	}
+}
+
+// ndjsonDecoder decodes the lines of a newline-delimited JSON body. The blank
+// lines are skipped.
+type ndjsonDecoder struct {
+	reader *bufio.Reader
+	body   JSONBody
+	line   int
+	err    error
+}
+
+func (d *ndjsonDecoder) Decode(dst any) error {
+	for d.err == nil {
+		line, err := d.reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			d.err = classifyRequestError(err)
+			break
+		}
+		d.line++
+		if len(bytes.TrimSpace(line)) > 0 {
+			body := d.body
+			body.DisallowTrailingData = true // one value per line
+			if err := body.Decode(bytes.NewReader(line), dst); err != nil {
+				d.err = fmt.Errorf("line %d: %w", d.line, classifyRequestError(err))
+				break
+			}
+			return nil
+		}
+		if err == io.EOF {
+			d.err = io.EOF
+		}
+	}
+	return d.err
+}
+
+// jsonArrayDecoder decodes the elements of a top-level JSON array.
+type jsonArrayDecoder struct {
+	decoder *json.Decoder
+	body    *JSONBody
+	started bool
+	err     error
+}
+
+func (d *jsonArrayDecoder) Decode(dst any) error {
+	if d.err == nil {
+		d.err = d.decode(dst)
+	}
+	return d.err
+}
+
+func (d *jsonArrayDecoder) decode(dst any) error {
+	if !d.started {
+		d.started = true
+		token, err := d.decoder.Token()
+		if err != nil {
+			return classifyRequestError(err)
+		}
+		if token != json.Delim('[') {
+			return fmt.Errorf("%w: expect a JSON array", ErrMalformedRequest)
+		}
+	}
+	if d.decoder.More() {
+		return classifyRequestError(d.body.decodeValue(d.decoder, dst))
+	}
+	if _, err := d.decoder.Token(); err != nil { // the closing "]"
+		return classifyRequestError(err)
+	}
+	if d.body.DisallowTrailingData {
+		if err := checkTrailingJSONData(d.decoder); err != nil {
+			return err
+		}
+	}
+	return io.EOF
+}
+
+// streamBody is the body of a request built by RequestBuilder.SetBodyStream.
+// The elements are encoded in a separate goroutine, which starts on the first
+// read, and stops when the body is closed.
+type streamBody struct {
+	ctx    context.Context
+	format string
+	elems  iter.Seq2[any, error]
+	once   sync.Once
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func newStreamBody(ctx context.Context, format string, elems iter.Seq2[any, error]) *streamBody {
+	reader, writer := io.Pipe()
+	return &streamBody{ctx: ctx, format: format, elems: elems, reader: reader, writer: writer}
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	b.once.Do(func() {
+		go func() {
+			b.writer.CloseWithError(b.write(b.writer))
+		}()
+	})
+	return b.reader.Read(p)
+}
+
+func (b *streamBody) Close() error {
+	return b.reader.Close()
+}
+
+// write encodes the elements to w, one per line. The elements of a JSON array
+// are separated by commas.
+func (b *streamBody) write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	delim := "["
+	for elem, err := range b.elems {
+		if err != nil {
+			return err
+		}
+		if b.ctx != nil && b.ctx.Err() != nil {
+			return b.ctx.Err()
+		}
+		if b.format != bodyFormatNDJSON {
+			if _, err := io.WriteString(w, delim); err != nil {
+				return err
+			}
+			delim = ","
+		}
+		if err := encoder.Encode(elem); err != nil {
+			return err
+		}
+	}
+	if b.format != bodyFormatNDJSON {
+		if delim == "[" { // empty array
+			delim = "[]\n"
+		} else {
+			delim = "]\n"
+		}
+		_, err := io.WriteString(w, delim)
+		return err
+	}
+	return nil
+}
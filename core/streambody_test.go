@@ -0,0 +1,296 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type Event struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type NDJSONSeqInput struct {
+	Events iter.Seq2[*Event, error] `in:"body=ndjson;strict"`
+}
+
+type NDJSONStreamInput struct {
+	Events Stream[Event] `in:"body=ndjson;maxbytes=64"`
+}
+
+type JSONArrayStreamInput struct {
+	Events *Stream[Event] `in:"body=json"`
+}
+
+type NDJSONSliceInput struct {
+	Events []Event `in:"body=ndjson"`
+}
+
+type NDJSONClientInput struct {
+	Events iter.Seq[Event] `in:"body=ndjson"`
+}
+
+type JSONArrayClientInput struct {
+	Events iter.Seq[Event] `in:"body=json"`
+}
+
+func newStreamRequest(body io.Reader) *http.Request {
+	r, _ := http.NewRequest("POST", "/events", body)
+	return r
+}
+
+// lineReader returns a reader of the lines, one line per read, and reports
+// how many lines have been read.
+func lineReader(lines ...string) (io.Reader, func() int) {
+	pr, pw := io.Pipe()
+	var read atomic.Int32
+	go func() {
+		for _, line := range lines {
+			if _, err := io.WriteString(pw, line+"\n"); err != nil {
+				return
+			}
+			read.Add(1)
+		}
+		pw.Close()
+	}()
+	return pr, func() int { return int(read.Load()) }
+}
+
+func TestStreamBody_NDJSONSeq2(t *testing.T) {
+	co, err := New(NDJSONSeqInput{})
+	assert.NoError(t, err)
+
+	body, linesRead := lineReader(`{"id": 1, "name": "a"}`, ``, `{"id": 2, "name": "b"}`, `{"id": 3, "name": "c"}`)
+	got, err := co.Decode(newStreamRequest(body))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, linesRead()) // nothing is read before consuming
+
+	var events []*Event
+	for event, err := range got.(*NDJSONSeqInput).Events {
+		assert.NoError(t, err)
+		events = append(events, event)
+		if event.ID == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []*Event{{1, "a"}, {2, "b"}}, events)
+	assert.Less(t, linesRead(), 4)
+
+	// An error stops the stream, with the line number.
+	got, err = co.Decode(newStreamRequest(strings.NewReader("{\"id\": 1}\n{\"id\": 2, \"extra\": true}\n{\"id\": 3}\n")))
+	assert.NoError(t, err)
+	var errs []error
+	for event, err := range got.(*NDJSONSeqInput).Events {
+		if err != nil {
+			errs = append(errs, err)
+			assert.Nil(t, event)
+		}
+	}
+	assert.Len(t, errs, 1)
+	assert.ErrorContains(t, errs[0], "line 2: ")
+	var coded *CodedError
+	assert.ErrorAs(t, errs[0], &coded)
+	assert.Equal(t, CodeUnknownField, coded.Code)
+
+	// One value per line.
+	got, err = co.Decode(newStreamRequest(strings.NewReader(`{"id": 1} {"id": 2}`)))
+	assert.NoError(t, err)
+	for _, err := range got.(*NDJSONSeqInput).Events {
+		assert.ErrorIs(t, err, ErrMalformedRequest)
+	}
+}
+
+func TestStreamBody_NDJSONStream(t *testing.T) {
+	co, err := New(NDJSONStreamInput{})
+	assert.NoError(t, err)
+
+	got, err := co.Decode(newStreamRequest(strings.NewReader("{\"id\": 1}\r\n{\"id\": 2}")))
+	assert.NoError(t, err)
+	stream := &got.(*NDJSONStreamInput).Events
+	var ids []int
+	for stream.Next() {
+		ids = append(ids, stream.Value().ID)
+	}
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, []int{1, 2}, ids)
+	assert.False(t, stream.Next())
+
+	// Too large, see maxbytes.
+	got, err = co.Decode(newStreamRequest(strings.NewReader(strings.Repeat("{\"id\": 1}\n", 10))))
+	assert.NoError(t, err)
+	stream = &got.(*NDJSONStreamInput).Events
+	for stream.Next() {
+	}
+	assert.ErrorIs(t, stream.Err(), ErrRequestTooLarge)
+}
+
+func TestStreamBody_JSONArray(t *testing.T) {
+	co, err := New(JSONArrayStreamInput{})
+	assert.NoError(t, err)
+
+	got, err := co.Decode(newStreamRequest(strings.NewReader(`[{"id": 1}, {"id": 2}, {"id": 3}]`)))
+	assert.NoError(t, err)
+	var ids []int
+	for event, err := range got.(*JSONArrayStreamInput).Events.All() {
+		assert.NoError(t, err)
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3}, ids)
+
+	for _, body := range []string{``, `{"id": 1}`, `[{"id": 1},`, `[{"id": 1} {"id": 2}]`} {
+		got, err := co.Decode(newStreamRequest(strings.NewReader(body)))
+		assert.NoError(t, err)
+		stream := got.(*JSONArrayStreamInput).Events
+		for stream.Next() {
+		}
+		assert.ErrorIs(t, stream.Err(), ErrMalformedRequest, body)
+	}
+}
+
+func TestStreamBody_NDJSONSlice(t *testing.T) {
+	co, err := New(NDJSONSliceInput{})
+	assert.NoError(t, err)
+
+	got, err := co.Decode(newStreamRequest(strings.NewReader("{\"id\": 1}\n{\"id\": 2}\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{ID: 1}, {ID: 2}}, got.(*NDJSONSliceInput).Events)
+
+	_, err = co.Decode(newStreamRequest(strings.NewReader("{\"id\": 1}\n{\"id\": \n")))
+	assert.ErrorIs(t, err, ErrMalformedRequest)
+
+	req, err := co.NewRequest("POST", "/events", &NDJSONSliceInput{Events: []Event{{ID: 1}, {ID: 2}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", req.Header.Get("Content-Type"))
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"id\":1,\"name\":\"\"}\n{\"id\":2,\"name\":\"\"}\n", string(body))
+}
+
+func TestStreamBody_NewRequest(t *testing.T) {
+	events := []Event{{1, "a"}, {2, "b"}}
+
+	co, err := New(NDJSONClientInput{})
+	assert.NoError(t, err)
+	req, err := co.NewRequest("POST", "/events", &NDJSONClientInput{Events: slices.Values(events)})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/x-ndjson", req.Header.Get("Content-Type"))
+
+	// Decode the request on the server side.
+	server, err := New(NDJSONSeqInput{})
+	assert.NoError(t, err)
+	got, err := server.Decode(req)
+	assert.NoError(t, err)
+	var decoded []Event
+	for event, err := range got.(*NDJSONSeqInput).Events {
+		assert.NoError(t, err)
+		decoded = append(decoded, *event)
+	}
+	assert.Equal(t, events, decoded)
+
+	// JSON array.
+	co, err = New(JSONArrayClientInput{})
+	assert.NoError(t, err)
+	for _, c := range []struct {
+		events   []Event
+		expected []Event
+	}{
+		{events, events},
+		{nil, []Event{}},
+	} {
+		req, err = co.NewRequest("POST", "/events", &JSONArrayClientInput{Events: slices.Values(c.events)})
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		var array []Event
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&array))
+		assert.Equal(t, c.expected, array)
+	}
+
+	// A nil sequence leaves the body unset.
+	req, err = co.NewRequest("POST", "/events", &JSONArrayClientInput{})
+	assert.NoError(t, err)
+	assert.Nil(t, req.Body)
+
+	// An iter.Seq can't be decoded.
+	_, err = co.Decode(newStreamRequest(strings.NewReader(`[]`)))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestRequestBuilder_SetBodyStream(t *testing.T) {
+	errBroken := errors.New("broken")
+	rb := NewRequestBuilder(context.Background())
+	rb.SetBodyStream("ndjson", func(yield func(any, error) bool) {
+		if yield(1, nil) {
+			yield(nil, errBroken)
+		}
+	})
+	body, err := io.ReadAll(rb.Body)
+	assert.ErrorIs(t, err, errBroken)
+	assert.Equal(t, "1\n", string(body))
+
+	// Closing the body stops the encoding.
+	rb = NewRequestBuilder(context.Background())
+	stopped := make(chan struct{})
+	rb.SetBodyStream("json", func(yield func(any, error) bool) {
+		defer close(stopped)
+		for i := 0; yield(i, nil); i++ {
+		}
+	})
+	buf := make([]byte, 8)
+	_, err = rb.Body.Read(buf)
+	assert.NoError(t, err)
+	assert.NoError(t, rb.Body.Close())
+	<-stopped
+}
+
+func TestStreamBody_Invalid(t *testing.T) {
+	for _, c := range []struct {
+		input any
+		err   string
+	}{
+		{struct {
+			Events iter.Seq2[Event, error] `in:"body=json,ndjson"`
+		}{}, "a stream requires exactly one format"},
+		{struct {
+			Events Stream[Event] `in:"body=xml"`
+		}{}, "a stream requires exactly one format"},
+		{struct {
+			Events []Event `in:"body=json,ndjson"`
+		}{}, "a stream requires exactly one format"},
+		{struct {
+			Events map[string]Event `in:"body=ndjson"`
+		}{}, "type mismatch"},
+	} {
+		_, err := New(c.input)
+		assert.ErrorContains(t, err, c.err)
+	}
+
+	assert.PanicsWithError(t, `httpin: reserved body format: "ndjson"`, func() {
+		RegisterBodyFormat("ndjson", &tomlBody{})
+	})
+}
+
+func TestStreamBody_AutoWithNDJSONContentType(t *testing.T) {
+	type AutoInput struct {
+		Events []Event `in:"body=auto"`
+	}
+	co, err := New(AutoInput{})
+	assert.NoError(t, err)
+
+	// "auto" doesn't include "ndjson", which is selected explicitly.
+	r := newStreamRequest(strings.NewReader("{\"id\": 1}\n{\"id\": 2}\n"))
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	_, err = co.Decode(r)
+	assert.ErrorIs(t, err, ErrUnsupportedMediaType)
+	assert.NotErrorIs(t, err, ErrUnknownBodyFormat)
+	assert.Equal(t, http.StatusUnsupportedMediaType, DefaultErrorStatusMapper(err))
+}
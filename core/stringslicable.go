@@ -15,9 +15,17 @@ type StringSlicable interface {
 
 func NewStringSlicable(rv reflect.Value, adapt AnyStringableAdaptor) (StringSlicable, error) {
 	if rv.Type().Implements(stringSliceableType) && rv.CanInterface() {
+		if rv.Kind() == reflect.Pointer && rv.CanSet() {
+			createInstanceIfNil(rv)
+		}
 		return rv.Interface().(StringSlicable), nil
 	}
 
+	// StringSlicable implemented with pointer receivers, e.g. sfv.List.
+	if rv.CanAddr() && reflect.PointerTo(rv.Type()).Implements(stringSliceableType) && rv.CanInterface() {
+		return rv.Addr().Interface().(StringSlicable), nil
+	}
+
 	if IsPatchField(rv.Type()) {
 		return NewStringSlicablePatchFieldWrapper(rv, adapt)
 	}
@@ -0,0 +1,456 @@
+// directive: "style"
+// https://ggicci.github.io/httpin/directives/style
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ggicci/owl"
+)
+
+// Serialization styles of the values in querystring, headers, forms, etc.
+// Which follow the definitions of OpenAPI, see
+// https://swagger.io/docs/specification/serialization/
+const (
+	// StyleForm is the default style. When exploded (by default), a list is
+	// rendered as repeated keys, e.g. ids=1&ids=2. Otherwise, the values are
+	// delimited by commas, e.g. ids=1,2.
+	StyleForm = "form"
+
+	// StyleSpaceDelimited renders a list as space separated values, e.g.
+	// ids=1%202.
+	StyleSpaceDelimited = "spaceDelimited"
+
+	// StylePipeDelimited renders a list as pipe separated values, e.g. ids=1|2.
+	StylePipeDelimited = "pipeDelimited"
+
+	// StyleDeepObject renders nested objects by using bracket notation, e.g.
+	// filter[status]=open&filter[owner][id]=42. Slices are rendered in the
+	// Rails/PHP style, e.g. tags[]=a&tags[]=b.
+	StyleDeepObject = "deepObject"
+)
+
+var styleDelimiters = map[string]string{
+	StyleForm:           ",",
+	StyleSpaceDelimited: " ",
+	StylePipeDelimited:  "|",
+}
+
+// fieldStyle describes how the values of a field are serialized. It is
+// specified by the "style", "explode" and "delim" directives, e.g.
+//
+//	type ListTasksInput struct {
+//	    Filter TaskFilter `in:"query=filter;style=deepObject"`
+//	    IDs    []int      `in:"query=ids;explode=false;delim=|"`
+//	}
+//
+// Like the "coder" directive, these directives will be removed from the
+// resolver during the resolver building phase, and the fieldStyle will be put
+// into Resolver.Context.
+//
+// When not exploded, the values are joined by the delimiter into a single
+// value. A delimiter or a backslash inside a value is escaped by a preceding
+// backslash, e.g. ["a,b", "c"] is rendered as `a\,b,c`.
+type fieldStyle struct {
+	Style   string
+	Explode bool
+	Delim   string
+}
+
+func (rtm *DirectiveRuntime) getFieldStyle() *fieldStyle {
+	if style := rtm.Resolver.Context.Value(ctxFieldStyle); style != nil {
+		return style.(*fieldStyle)
+	}
+	return nil
+}
+
+func (rtm *DirectiveRuntime) isDeepObjectStyle() bool {
+	style := rtm.getFieldStyle()
+	return style != nil && style.Style == StyleDeepObject
+}
+
+// isDelimited tells whether the values are joined by a delimiter.
+func (s *fieldStyle) isDelimited() bool {
+	return s != nil && !s.Explode && s.Delim != ""
+}
+
+// splitValues splits every delimited value into a list of values.
+func (s *fieldStyle) splitValues(values []string) []string {
+	if !s.isDelimited() {
+		return values
+	}
+	var result []string
+	for _, value := range values {
+		result = append(result, splitDelimited(value, s.Delim)...)
+	}
+	return result
+}
+
+// joinValues joins the values into a single delimited value.
+func (s *fieldStyle) joinValues(values []string) []string {
+	if !s.isDelimited() || len(values) == 0 {
+		return values
+	}
+	return []string{joinDelimited(values, s.Delim)}
+}
+
+// reserveStyleDirective removes the "style", "explode" and "delim" directives
+// from the resolver, and puts the parsed fieldStyle into Resolver.Context.
+func reserveStyleDirective(r *owl.Resolver) error {
+	styleDirective := r.RemoveDirective("style")
+	explodeDirective := r.RemoveDirective("explode")
+	delimDirective := r.RemoveDirective("delim")
+	if styleDirective == nil && explodeDirective == nil && delimDirective == nil {
+		return nil
+	}
+
+	style := &fieldStyle{Style: StyleForm}
+	if styleDirective != nil {
+		if len(styleDirective.Argv) == 0 || styleDirective.Argv[0] == "" {
+			return fmt.Errorf("directive style: missing style name")
+		}
+		style.Style = styleDirective.Argv[0]
+	}
+	if style.Style != StyleDeepObject && styleDelimiters[style.Style] == "" {
+		return fmt.Errorf("directive style: unknown style %q", style.Style)
+	}
+
+	// By OpenAPI, explode defaults to true only for the form style.
+	style.Explode = style.Style == StyleForm || style.Style == StyleDeepObject
+	style.Delim = styleDelimiters[style.Style]
+	if explodeDirective != nil {
+		if len(explodeDirective.Argv) == 0 {
+			return fmt.Errorf("directive explode: missing value")
+		}
+		explode, err := strconv.ParseBool(explodeDirective.Argv[0])
+		if err != nil {
+			return fmt.Errorf("directive explode: invalid value %q", explodeDirective.Argv[0])
+		}
+		style.Explode = explode
+	}
+	if delimDirective != nil {
+		// The delimiter "," has been split into two empty args by the parser.
+		delim := strings.Join(delimDirective.Argv, ",")
+		if utf8.RuneCountInString(delim) != 1 || delim == "\\" {
+			return fmt.Errorf("directive delim: invalid delimiter %q, must be a single character other than backslash", delim)
+		}
+		style.Delim = delim
+		if explodeDirective == nil {
+			style.Explode = false // a delimiter implies explode=false
+		}
+	}
+
+	if style.Style == StyleDeepObject {
+		if !style.Explode || delimDirective != nil {
+			return fmt.Errorf("directive style: %s style cannot be used with explode=false or delim", StyleDeepObject)
+		}
+		if err := validateDeepObjectType(r.Type); err != nil {
+			return fmt.Errorf("directive style: %w", err)
+		}
+	}
+	if style.isDelimited() {
+		if err := validateDelimitedType(r.Type); err != nil {
+			return fmt.Errorf("directive style: %w", err)
+		}
+	}
+
+	r.Context = context.WithValue(r.Context, ctxFieldStyle, style)
+	return nil
+}
+
+func validateDelimitedType(t reflect.Type) error {
+	if t.Kind() == reflect.Map {
+		t = t.Elem() // map fields with wildcard keys
+	}
+	_, typeKind := BaseTypeOf(t)
+	if isFileType(t) || isByteSliceType(t) ||
+		(typeKind != TypeKindTSlice && typeKind != TypeKindPatchTSlice) {
+		return fmt.Errorf("delimited values require a slice field, got %v", t)
+	}
+	return nil
+}
+
+func validateDeepObjectType(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isFileType(t) {
+		return fmt.Errorf("%s style cannot be used on a file type field", StyleDeepObject)
+	}
+	if isSliceType(t) && isDeepObjectLeaf(t) {
+		return nil
+	}
+	if isDeepObjectLeaf(t) || (t.Kind() != reflect.Struct && t.Kind() != reflect.Map) {
+		return fmt.Errorf("%s style requires a struct, map or slice field, got %v", StyleDeepObject, t)
+	}
+	if t.Kind() == reflect.Map && t.Key().Kind() != reflect.String {
+		return fmt.Errorf("%s style requires map keys of string kind, got %v", StyleDeepObject, t.Key())
+	}
+	return nil
+}
+
+// isDeepObjectLeaf tells whether a value of the given type can be converted
+// from/to a string slice directly. Which will not be expanded by the bracket
+// notation.
+func isDeepObjectLeaf(t reflect.Type) bool {
+	_, err := NewStringSlicable(reflect.New(t).Elem(), nil)
+	return err == nil
+}
+
+// deepObjectFieldName returns the name of a struct field used in the bracket
+// notation. The name in the json tag takes precedence over the field name.
+func deepObjectFieldName(f reflect.StructField) (name string, omitempty bool, ok bool) {
+	if !f.IsExported() {
+		return "", false, false
+	}
+	name = f.Name
+	if tag, has := f.Tag.Lookup("json"); has {
+		tagName, opts, _ := strings.Cut(tag, ",")
+		if tagName == "-" && opts == "" {
+			return "", false, false
+		}
+		if tagName != "" {
+			name = tagName
+		}
+		omitempty = strings.Contains(","+opts+",", ",omitempty,")
+	}
+	return name, omitempty, true
+}
+
+// parseDeepObjectKey parses the key in bracket notation, e.g. filter[owner][id],
+// and returns the path segments after the given name, i.e. ["owner", "id"].
+// A trailing "[]" is dropped, which indicates the value is a list.
+func parseDeepObjectKey(name, key string) ([]string, bool) {
+	if !strings.HasPrefix(key, name) {
+		return nil, false
+	}
+	rest := key[len(name):]
+	var path []string
+	for rest != "" {
+		if rest[0] != '[' {
+			return nil, false
+		}
+		end := strings.IndexByte(rest, ']')
+		if end == -1 {
+			return nil, false
+		}
+		path = append(path, rest[1:end])
+		rest = rest[end+1:]
+	}
+	if len(path) > 0 && path[len(path)-1] == "" {
+		path = path[:len(path)-1]
+	}
+	return path, true
+}
+
+// deepObjectNode is a node of the tree built from the keys in bracket notation.
+type deepObjectNode struct {
+	values   []string
+	children map[string]*deepObjectNode
+}
+
+func (n *deepObjectNode) insert(path []string, values []string) {
+	for _, segment := range path {
+		if n.children == nil {
+			n.children = make(map[string]*deepObjectNode)
+		}
+		child := n.children[segment]
+		if child == nil {
+			child = &deepObjectNode{}
+			n.children[segment] = child
+		}
+		n = child
+	}
+	n.values = append(n.values, values...)
+}
+
+func (n *deepObjectNode) decode(rv reflect.Value, key string) error {
+	if isDeepObjectLeaf(rv.Type()) {
+		if len(n.values) == 0 {
+			return nil
+		}
+		decoder, err := NewStringSlicable(rv, nil)
+		if err == nil {
+			err = decoder.FromStringSlice(n.values)
+		}
+		if err != nil {
+			return &fieldError{key, n.values, err}
+		}
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return n.decode(rv.Elem(), key)
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			name, _, ok := deepObjectFieldName(rv.Type().Field(i))
+			if !ok || n.children[name] == nil {
+				continue
+			}
+			if err := n.children[name].decode(rv.Field(i), key+"["+name+"]"); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return &fieldError{key, n.values, fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())}
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, name := range sortedKeys(n.children) {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			if err := n.children[name].decode(elem, key+"["+name+"]"); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()), elem)
+		}
+		return nil
+	default:
+		return &fieldError{key, n.values, fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())}
+	}
+}
+
+// decodeDeepObject decodes the values whose keys are in bracket notation and
+// prefixed by the given name into rv.
+func decodeDeepObject(rv reflect.Value, name string, form map[string][]string) (bool, error) {
+	root := &deepObjectNode{}
+	found := false
+	for _, key := range sortedKeys(form) {
+		if path, ok := parseDeepObjectKey(name, key); ok {
+			root.insert(path, form[key])
+			found = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	return true, root.decode(rv, name)
+}
+
+// encodeDeepObject encodes rv into values whose keys are in bracket notation
+// and prefixed by the given key.
+func encodeDeepObject(rv reflect.Value, key string, set func(key string, values []string)) error {
+	if isDeepObjectLeaf(rv.Type()) {
+		if rv.Kind() == reflect.Pointer && rv.IsNil() {
+			return nil
+		}
+		encoder, err := NewStringSlicable(rv, nil)
+		if err != nil {
+			return err
+		}
+		values, err := encoder.ToStringSlice()
+		if err != nil {
+			return err
+		}
+		if isSliceType(rv.Type()) && !isByteSliceType(rv.Type()) {
+			key += "[]"
+		}
+		set(key, values)
+		return nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return encodeDeepObject(rv.Elem(), key, set)
+	case reflect.Struct:
+		for i := 0; i < rv.NumField(); i++ {
+			name, omitempty, ok := deepObjectFieldName(rv.Type().Field(i))
+			if !ok || (omitempty && rv.Field(i).IsZero()) {
+				continue
+			}
+			if err := encodeDeepObject(rv.Field(i), key+"["+name+"]", set); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		for _, k := range keys {
+			// Map elements are not addressable, make a copy.
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			elem.Set(rv.MapIndex(k))
+			if err := encodeDeepObject(elem, key+"["+k.String()+"]", set); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+	}
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitDelimited splits s by the unescaped delimiter. A backslash escapes the
+// following character, e.g. `a\,b,c` -> ["a,b", "c"]. An empty string results
+// in an empty list.
+func splitDelimited(s string, delim string) []string {
+	if s == "" {
+		return nil
+	}
+	var parts []string
+	var b strings.Builder
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			b.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case string(c) == delim:
+			parts = append(parts, b.String())
+			b.Reset()
+		default:
+			b.WriteRune(c)
+		}
+	}
+	if escaped {
+		b.WriteRune('\\') // keep the trailing backslash as is
+	}
+	return append(parts, b.String())
+}
+
+// joinDelimited joins the values by the delimiter, the delimiters and
+// backslashes inside the values are escaped by a backslash.
+func joinDelimited(values []string, delim string) string {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		var b strings.Builder
+		for _, c := range value {
+			if c == '\\' || string(c) == delim {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(c)
+		}
+		escaped[i] = b.String()
+	}
+	return strings.Join(escaped, delim)
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type TaskOwner struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type TaskFilter struct {
+	Status string     `json:"status"`
+	Owner  *TaskOwner `json:"owner"`
+	Labels []string   `json:"labels,omitempty"`
+	Hidden bool       `json:"-"`
+}
+
+type ListTasksInput struct {
+	Filter TaskFilter        `in:"query=filter;style=deepObject"`
+	Tags   []string          `in:"query=tags;style=deepObject"`
+	Meta   map[string]string `in:"form=meta;style=deepObject"`
+	Page   int               `in:"query=page"`
+}
+
+func TestStyleDeepObject_Decode(t *testing.T) {
+	co, err := New(ListTasksInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/tasks?filter[status]=open&filter[owner][id]=42&filter[labels][]=bug&filter[labels][]=p0&filter[Hidden]=true&tags[]=a&tags[]=b&meta[env]=prod&page=2", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &ListTasksInput{
+		Filter: TaskFilter{
+			Status: "open",
+			Owner:  &TaskOwner{ID: 42},
+			Labels: []string{"bug", "p0"},
+		},
+		Tags: []string{"a", "b"},
+		Meta: map[string]string{"env": "prod"},
+		Page: 2,
+	}, got)
+}
+
+func TestStyleDeepObject_Decode_Absent(t *testing.T) {
+	co, err := New(ListTasksInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/tasks?filters[status]=open", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &ListTasksInput{}, got)
+}
+
+func TestStyleDeepObject_Decode_InvalidValue(t *testing.T) {
+	co, err := New(ListTasksInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/tasks?filter[owner][id]=abc", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Filter", invalidField.Field)
+	assert.Equal(t, "filter[owner][id]", invalidField.Key)
+	assert.Equal(t, []string{"abc"}, invalidField.Value)
+}
+
+func TestStyleDeepObject_NewRequest(t *testing.T) {
+	co, err := New(ListTasksInput{})
+	assert.NoError(t, err)
+
+	req, err := co.NewRequest("POST", "/tasks", &ListTasksInput{
+		Filter: TaskFilter{
+			Status: "open",
+			Owner:  &TaskOwner{ID: 42},
+			Hidden: true,
+		},
+		Tags: []string{"a", "b"},
+		Meta: map[string]string{"env": "prod", "region": "eu"},
+		Page: 2,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, url.Values{
+		"filter[status]":    {"open"},
+		"filter[owner][id]": {"42"},
+		"tags[]":            {"a", "b"},
+		"page":              {"2"},
+	}, req.URL.Query())
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "meta%5Benv%5D=prod&meta%5Bregion%5D=eu", string(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Round trip.
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, &ListTasksInput{
+		Filter: TaskFilter{
+			Status: "open",
+			Owner:  &TaskOwner{ID: 42},
+		},
+		Tags: []string{"a", "b"},
+		Meta: map[string]string{"env": "prod", "region": "eu"},
+		Page: 2,
+	}, got)
+}
+
+func TestStyleDirective_Invalid(t *testing.T) {
+	type MissingStyleName struct {
+		Filter TaskFilter `in:"query=filter;style"`
+	}
+	_, err := New(MissingStyleName{})
+	assert.ErrorContains(t, err, "directive style: missing style name")
+
+	type UnknownStyle struct {
+		Filter TaskFilter `in:"query=filter;style=matrix"`
+	}
+	_, err = New(UnknownStyle{})
+	assert.ErrorContains(t, err, "directive style: unknown style \"matrix\"")
+
+	type DeepObjectOnScalar struct {
+		Status string `in:"query=status;style=deepObject"`
+	}
+	_, err = New(DeepObjectOnScalar{})
+	assert.ErrorContains(t, err, "deepObject style requires a struct, map or slice field")
+
+	type DeepObjectOnIntKeyMap struct {
+		Filter map[int]string `in:"query=filter;style=deepObject"`
+	}
+	_, err = New(DeepObjectOnIntKeyMap{})
+	assert.ErrorContains(t, err, "deepObject style requires map keys of string kind")
+}
+
+func TestParseDeepObjectKey(t *testing.T) {
+	for _, c := range []struct {
+		key      string
+		expected []string
+		ok       bool
+	}{
+		{"filter", nil, true},
+		{"filter[]", []string{}, true},
+		{"filter[status]", []string{"status"}, true},
+		{"filter[owner][id]", []string{"owner", "id"}, true},
+		{"filters[status]", nil, false},
+		{"filter[owner", nil, false},
+		{"filter[owner]id", nil, false},
+	} {
+		path, ok := parseDeepObjectKey("filter", c.key)
+		assert.Equal(t, c.ok, ok, c.key)
+		assert.Equal(t, c.expected, path, c.key)
+	}
+}
+
+type ListItemsInput struct {
+	IDs      []int                  `in:"query=ids;explode=false;delim=,"`
+	Names    []string               `in:"query=names;style=pipeDelimited"`
+	Words    []string               `in:"query=words;style=spaceDelimited"`
+	Tags     patch.Field[[]string]  `in:"header=x-tags;style=form;explode=false"`
+	Statuses []string               `in:"query=status;style=pipeDelimited;explode=true"`
+	Scores   patch.Field[[]float64] `in:"form=scores;delim=:"`
+}
+
+func TestStyleDelimited_Decode(t *testing.T) {
+	co, err := New(ListItemsInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", `/items?ids=1,2,3&names=a|b\|c|d\\&words=hello%20world&status=open&status=closed`, nil)
+	r.Header.Set("X-Tags", "x,y")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &ListItemsInput{
+		IDs:      []int{1, 2, 3},
+		Names:    []string{"a", "b|c", `d\`},
+		Words:    []string{"hello", "world"},
+		Tags:     patch.Field[[]string]{Value: []string{"x", "y"}, Valid: true},
+		Statuses: []string{"open", "closed"},
+	}, got)
+}
+
+func TestStyleDelimited_Decode_InvalidValue(t *testing.T) {
+	co, err := New(ListItemsInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/items?ids=1,x", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "IDs", invalidField.Field)
+	assert.Equal(t, []string{"1,x"}, invalidField.Value)
+}
+
+func TestStyleDelimited_NewRequest(t *testing.T) {
+	co, err := New(ListItemsInput{})
+	assert.NoError(t, err)
+
+	input := &ListItemsInput{
+		IDs:      []int{1, 2, 3},
+		Names:    []string{"a", "b|c", `d\`},
+		Words:    []string{"hello", "world"},
+		Tags:     patch.Field[[]string]{Value: []string{"x", "y"}, Valid: true},
+		Statuses: []string{"open", "closed"},
+		Scores:   patch.Field[[]float64]{Value: []float64{1.5, 2}, Valid: true},
+	}
+	req, err := co.NewRequest("POST", "/items", input)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Values{
+		"ids":    {"1,2,3"},
+		"names":  {`a|b\|c|d\\`},
+		"words":  {"hello world"},
+		"status": {"open", "closed"},
+	}, req.URL.Query())
+	assert.Equal(t, "x,y", req.Header.Get("X-Tags"))
+
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, input, got)
+}
+
+func TestStyleDelimited_Invalid(t *testing.T) {
+	type DelimitedScalar struct {
+		ID string `in:"query=id;explode=false"`
+	}
+	_, err := New(DelimitedScalar{})
+	assert.ErrorContains(t, err, "delimited values require a slice field")
+
+	type InvalidExplode struct {
+		IDs []int `in:"query=ids;explode=no"`
+	}
+	_, err = New(InvalidExplode{})
+	assert.ErrorContains(t, err, "directive explode: invalid value \"no\"")
+
+	type InvalidDelim struct {
+		IDs []int `in:"query=ids;delim=ab"`
+	}
+	_, err = New(InvalidDelim{})
+	assert.ErrorContains(t, err, "directive delim: invalid delimiter \"ab\"")
+
+	type DeepObjectNotExploded struct {
+		Filter TaskFilter `in:"query=filter;style=deepObject;explode=false"`
+	}
+	_, err = New(DeepObjectNotExploded{})
+	assert.ErrorContains(t, err, "deepObject style cannot be used with explode=false or delim")
+}
+
+func TestSplitJoinDelimited(t *testing.T) {
+	for _, c := range []struct {
+		values []string
+		joined string
+	}{
+		{[]string{"a", "b"}, "a,b"},
+		{[]string{"a,b", "c"}, `a\,b,c`},
+		{[]string{`a\`, "b"}, `a\\,b`},
+		{[]string{"", ""}, ","},
+	} {
+		assert.Equal(t, c.joined, joinDelimited(c.values, ","))
+		assert.Equal(t, c.values, splitDelimited(c.joined, ","))
+	}
+	assert.Nil(t, splitDelimited("", ","))
+	assert.Equal(t, []string{`a\`}, splitDelimited(`a\`, ","))
+}
@@ -0,0 +1,104 @@
+// directive: "trim", "lower", "upper", "nfc", "nfkc", "collapse"
+// https://ggicci.github.io/httpin/directives/transform
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ggicci/owl"
+	"golang.org/x/text/unicode/norm"
+)
+
+// transformers are the functions of the transform directives. The directives
+// normalize the string values of a field, e.g.
+//
+//	type SignUpInput struct {
+//		Email string   `in:"form=email;trim;lower"`
+//		Tags  []string `in:"query=tag;trim;nfkc"`
+//	}
+//
+// Like the "coder" directive, these directives will be removed from the
+// resolver during the resolver building phase, and the fieldTransforms will be
+// put into Resolver.Context. The transforms run in the order of the
+// directives, on every value after the extraction and before the type
+// conversion. When encoding, the outgoing values are transformed the same way.
+// Using them with a directive of transformlessDirectives, e.g. "body", is an
+// error.
+var transformers = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"nfc":   norm.NFC.String,
+	"nfkc":  norm.NFKC.String,
+	// collapse trims the value and replaces every run of whitespace with a
+	// single space, e.g. " hello \t world " becomes "hello world".
+	"collapse": func(s string) string { return strings.Join(strings.Fields(s), " ") },
+}
+
+// transformlessDirectives are the source directives which don't extract the
+// string values of a field, so the transform directives can't apply to them.
+var transformlessDirectives = []string{"body", "jsonptr", "ctx", "auth", "clientip", "request"}
+
+type fieldTransforms []func(string) string
+
+func (rtm *DirectiveRuntime) getFieldTransforms() fieldTransforms {
+	if transforms := rtm.Resolver.Context.Value(ctxFieldTransforms); transforms != nil {
+		return transforms.(fieldTransforms)
+	}
+	return nil
+}
+
+// apply transforms every value. The given values are left untouched.
+func (ts fieldTransforms) apply(values []string) []string {
+	if len(ts) == 0 {
+		return values
+	}
+	result := make([]string, len(values))
+	for i, value := range values {
+		for _, transform := range ts {
+			value = transform(value)
+		}
+		result[i] = value
+	}
+	return result
+}
+
+// reserveTransformDirectives removes the transform directives from the
+// resolver, and puts the fieldTransforms into Resolver.Context.
+func reserveTransformDirectives(r *owl.Resolver) error {
+	var transforms fieldTransforms
+	var names []string
+	for _, d := range r.Directives {
+		if transform := transformers[d.Name]; transform != nil {
+			if len(d.Argv) > 0 {
+				return fmt.Errorf("directive %s: unexpected arguments %v", d.Name, d.Argv)
+			}
+			transforms = append(transforms, transform)
+			names = append(names, d.Name)
+		}
+	}
+	if len(transforms) == 0 {
+		return nil
+	}
+	for _, name := range names {
+		r.RemoveDirective(name)
+	}
+
+	if isFileType(r.Type) {
+		return fmt.Errorf("directive %s: cannot be used on a file type field", names[0])
+	}
+	if style, ok := r.Context.Value(ctxFieldStyle).(*fieldStyle); ok && style.Style == StyleDeepObject {
+		return fmt.Errorf("directive %s: cannot be used with %s style", names[0], StyleDeepObject)
+	}
+	for _, name := range transformlessDirectives {
+		if r.GetDirective(name) != nil {
+			return fmt.Errorf("directive %s: cannot be used with the %s directive", names[0], name)
+		}
+	}
+
+	r.Context = context.WithValue(r.Context, ctxFieldTransforms, transforms)
+	return nil
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type SignUpInput struct {
+	Email    string              `in:"form=email;trim;lower"`
+	Name     patch.Field[string] `in:"form=name;collapse"`
+	Tags     []string            `in:"query=tag;trim;upper"`
+	Codes    []string            `in:"query=codes;explode=false;trim"`
+	Nickname string              `in:"query=nickname;nfkc"`
+	Title    string              `in:"query=title;nfc"`
+	Age      int                 `in:"query=age;trim"`
+	Labels   map[string]string   `in:"header=X-Label-*;lower"`
+}
+
+func TestTransformDirectives_Decode(t *testing.T) {
+	co, err := New(SignUpInput{})
+	assert.NoError(t, err)
+
+	r := newMultipartFormRequestFromMap(map[string]any{
+		"email": "  Alice@Example.COM ",
+		"name":  " Alice \t  in\n Wonderland ",
+	})
+	r.URL.RawQuery = "tag=+go+&tag=rust&codes=+a+,b+&nickname=%EF%BC%A1%EF%BD%8C%EF%BD%89%EF%BD%83%EF%BD%85&title=Cafe%CC%81&age=+18+"
+	r.Header.Set("X-Label-Env", "PROD")
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &SignUpInput{
+		Email:    "alice@example.com",
+		Name:     patch.Field[string]{Value: "Alice in Wonderland", Valid: true},
+		Tags:     []string{"GO", "RUST"},
+		Codes:    []string{"a", "b"},
+		Nickname: "Alice", // fullwidth letters
+		Title:    "Café",
+		Age:      18,
+		Labels:   map[string]string{"Env": "prod"},
+	}, got)
+}
+
+func TestTransformDirectives_NewRequest(t *testing.T) {
+	co, err := New(SignUpInput{})
+	assert.NoError(t, err)
+
+	req, err := co.NewRequest("POST", "/signup", &SignUpInput{
+		Email:  " Alice@Example.COM",
+		Name:   patch.Field[string]{Value: "Alice  in Wonderland ", Valid: true},
+		Tags:   []string{" go", "rust "},
+		Codes:  []string{" a", "b "},
+		Labels: map[string]string{"Env": "PROD"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, req.ParseForm())
+	assert.Equal(t, "alice@example.com", req.PostForm.Get("email"))
+	assert.Equal(t, "Alice in Wonderland", req.PostForm.Get("name"))
+	assert.Equal(t, []string{"GO", "RUST"}, req.URL.Query()["tag"])
+	assert.Equal(t, "a,b", req.URL.Query().Get("codes"))
+	assert.Equal(t, "prod", req.Header.Get("X-Label-Env"))
+}
+
+func TestTransformDirectives_New_Errors(t *testing.T) {
+	type InputWithArgs struct {
+		Email string `in:"query=email;trim=all"`
+	}
+	_, err := New(InputWithArgs{})
+	assert.ErrorContains(t, err, "directive trim: unexpected arguments")
+
+	type InputDeepObject struct {
+		Filter map[string]string `in:"query=filter;style=deepObject;lower"`
+	}
+	_, err = New(InputDeepObject{})
+	assert.ErrorContains(t, err, "directive lower: cannot be used with deepObject style")
+
+	type InputFile struct {
+		Avatar *File `in:"form=avatar;trim"`
+	}
+	_, err = New(InputFile{})
+	assert.ErrorContains(t, err, "directive trim: cannot be used on a file type field")
+
+	for _, input := range []any{
+		struct {
+			Payload string `in:"body=text;trim"`
+		}{},
+		struct {
+			Email string `in:"jsonptr=/email;lower"`
+		}{},
+		struct {
+			Token string `in:"auth=bearer;trim"`
+		}{},
+		struct {
+			Tenant string `in:"ctx=tenant;upper"`
+		}{},
+		struct {
+			IP string `in:"clientip;lower"`
+		}{},
+		struct {
+			Host string `in:"request=host;lower"`
+		}{},
+	} {
+		_, err = New(input)
+		assert.ErrorContains(t, err, "cannot be used with the", input)
+	}
+
+	assert.PanicsWithError(t, "httpin: reserved executor name: \"trim\"", func() {
+		RegisterDirective("trim", noopDirective)
+	})
+}
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+// Validator is an optional interface of the input struct. When implemented,
+// Validate is called after all the directives have run, i.e. the input struct
+// has been fully decoded. It's the place to check the rules across fields,
+// e.g.
+//
+//	func (in *ListEventsInput) Validate() error {
+//		if in.End.Before(in.Start) {
+//			return core.NewFieldValidationError("End", errors.New("must not be before start"))
+//		}
+//		return nil
+//	}
+//
+// The returned error is converted to an InvalidFieldError, multiple errors
+// joined by errors.Join are converted to a MultiInvalidFieldError.
+type Validator interface {
+	Validate() error
+}
+
+// ContextValidator is the context-aware variant of Validator. The context of
+// the HTTP request is passed to Validate.
+type ContextValidator interface {
+	Validate(ctx context.Context) error
+}
+
+// NewFieldValidationError creates an InvalidFieldError for the given field,
+// which can be returned by Validator and ContextValidator. The Directive of the
+// error is "validate". The Code of the error is CodeInvalid unless err is a
+// CodedError.
+func NewFieldValidationError(field string, err error) *InvalidFieldError {
+	code, params := errorCodeOf(err)
+	return &InvalidFieldError{
+		err:          err,
+		Field:        field,
+		Directive:    "validate",
+		ErrorMessage: err.Error(),
+		Code:         code,
+		Params:       params,
+	}
+}
+
+// validateInput calls the Validate method of the decoded input if it
+// implements Validator or ContextValidator.
+func validateInput(ctx context.Context, input any) error {
+	var err error
+	switch v := input.(type) {
+	case Validator:
+		err = v.Validate()
+	case ContextValidator:
+		err = v.Validate(ctx)
+	default:
+		return nil
+	}
+	if err == nil {
+		return nil
+	}
+	return newValidationError(err)
+}
+
+func newValidationError(err error) error {
+	var (
+		invalidField  *InvalidFieldError
+		invalidFields MultiInvalidFieldError
+	)
+	if errors.As(err, &invalidFields) {
+		return invalidFields
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs := joined.Unwrap()
+		if len(errs) == 1 {
+			return newValidationError(errs[0])
+		}
+		for _, err := range errs {
+			switch err := newValidationError(err).(type) {
+			case MultiInvalidFieldError:
+				invalidFields = append(invalidFields, err...)
+			case *InvalidFieldError:
+				invalidFields = append(invalidFields, err)
+			}
+		}
+		return invalidFields
+	}
+	if errors.As(err, &invalidField) {
+		return invalidField
+	}
+	return NewFieldValidationError("", err)
+}
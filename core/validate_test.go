@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type DateRangeInput struct {
+	Start int `in:"query=start"`
+	End   int `in:"query=end"`
+}
+
+func (in *DateRangeInput) Validate() error {
+	if in.End < in.Start {
+		return NewFieldValidationError("End", errors.New("must not be less than start"))
+	}
+	return nil
+}
+
+type LookupInput struct {
+	ID   string `in:"query=id"`
+	Slug string `in:"query=slug"`
+}
+
+type ctxKeyStrict struct{}
+
+func (in LookupInput) Validate(ctx context.Context) error {
+	var errs []error
+	if in.ID == "" && in.Slug == "" {
+		errs = append(errs, errors.New("either id or slug is required"))
+	}
+	if ctx.Value(ctxKeyStrict{}) == true && in.ID != "" && in.Slug != "" {
+		errs = append(errs,
+			NewFieldValidationError("ID", errors.New("conflicts with slug")),
+			NewFieldValidationError("Slug", errors.New("conflicts with id")),
+		)
+	}
+	return errors.Join(errs...)
+}
+
+func TestCore_DecodeTo_Validator(t *testing.T) {
+	co, err := New(DateRangeInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/events?start=1&end=2", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &DateRangeInput{Start: 1, End: 2}, got)
+
+	r, _ = http.NewRequest("GET", "/events?start=2&end=1", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "End", invalidField.Field)
+	assert.Equal(t, "validate", invalidField.Directive)
+	assert.Equal(t, "must not be less than start", invalidField.ErrorMessage)
+
+	// Not validated when the directives failed.
+	r, _ = http.NewRequest("GET", "/events?start=x", nil)
+	_, err = co.Decode(r)
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Start", invalidField.Field)
+	assert.Equal(t, "query", invalidField.Directive)
+}
+
+func TestCore_DecodeTo_ContextValidator(t *testing.T) {
+	co, err := New(LookupInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/lookup?id=1", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &LookupInput{ID: "1"}, got)
+
+	r, _ = http.NewRequest("GET", "/lookup", nil)
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Empty(t, invalidField.Field)
+	assert.Equal(t, "either id or slug is required", invalidField.ErrorMessage)
+
+	r, _ = http.NewRequest("GET", "/lookup?id=1&slug=a", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeyStrict{}, true))
+	_, err = co.Decode(r)
+	var invalidFields MultiInvalidFieldError
+	assert.ErrorAs(t, err, &invalidFields)
+	assert.Len(t, invalidFields, 2)
+	assert.Equal(t, "ID", invalidFields[0].Field)
+	assert.Equal(t, "Slug", invalidFields[1].Field)
+}
+
+func TestCore_DecodeTo_Validator_ErrorHandler(t *testing.T) {
+	co, err := New(DateRangeInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/events?start=2&end=1", nil)
+	_, err = co.Decode(r)
+	rw := httptest.NewRecorder()
+	defaultErrorHandler(rw, r, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	assert.JSONEq(t, `{"field":"End","directive":"validate","key":"","value":null,"error":"must not be less than start","code":"invalid","message":"The value is invalid."}`, rw.Body.String())
+}
@@ -0,0 +1,389 @@
+// directive: "min", "max", "len", "minlen", "maxlen", "pattern", "enum", "format"
+// https://ggicci.github.io/httpin/directives/validation
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ggicci/owl"
+)
+
+// validationDirectives are the names of the validation directives.
+var validationDirectives = []string{"min", "max", "len", "minlen", "maxlen", "pattern", "enum", "format"}
+
+// DirectiveValidation implements the validation executors who check the value
+// of the field, e.g.
+//
+//	type ListUsersInput struct {
+//		Page     int      `in:"query=page;min=1"`
+//		PerPage  int      `in:"query=per_page;min=1;max=100"`
+//		Username string   `in:"query=username;minlen=3;maxlen=20;pattern=^[a-z0-9_]+$"`
+//		Sort     string   `in:"query=sort;enum=asc|desc"`
+//		Email    string   `in:"query=email;format=email"`
+//		IDs      []string `in:"query=id;maxlen=10;format=uuid"`
+//	}
+//
+// The "len", "minlen" and "maxlen" directives check the length of a string (in
+// runes), a slice or a map. The other directives check the value itself, every
+// element is checked for slices. Pointers and patch.Field are unwrapped, nil
+// pointers and invalid patch fields are not checked. When decoding, the zero
+// values of the fields which have not been set by former directives are not
+// checked, use "required" to require the presence of a field. When encoding,
+// the zero values are not checked, use "nonzero" to reject them.
+//
+// The arguments are parsed, and the patterns are compiled, when calling New.
+type DirectiveValidation struct{}
+
+func (*DirectiveValidation) Decode(rtm *DirectiveRuntime) error {
+	if !rtm.IsFieldSet() && rtm.Value.Elem().IsZero() {
+		return nil
+	}
+	return rtm.validate(rtm.Value.Elem())
+}
+
+func (*DirectiveValidation) Encode(rtm *DirectiveRuntime) error {
+	rv, ok := unwrapValidationValue(rtm.Value)
+	if !ok || rv.IsZero() {
+		return nil
+	}
+	return rtm.validate(rv)
+}
+
+// fieldValidator is a compiled validation directive.
+type fieldValidator struct {
+	onLength bool // check the length of the value, rather than the elements
+	check    func(reflect.Value) error
+}
+
+type fieldValidators map[string]*fieldValidator // directive name: validator
+
+func (rtm *DirectiveRuntime) validate(rv reflect.Value) error {
+	validators, _ := rtm.Resolver.Context.Value(ctxFieldValidators).(fieldValidators)
+	validator := validators[rtm.Directive.Name]
+	if validator == nil {
+		return nil
+	}
+
+	rv, ok := unwrapValidationValue(rv)
+	if !ok {
+		return nil
+	}
+	if validator.onLength || !isSliceType(rv.Type()) || isByteSliceType(rv.Type()) {
+		return validator.check(rv)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		elem, ok := unwrapValidationValue(rv.Index(i))
+		if !ok {
+			continue
+		}
+		if err := validator.check(elem); err != nil {
+			return fmt.Errorf("element at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// unwrapValidationValue unwraps the patch fields and the pointers. Returns
+// false if there's nothing to validate.
+func unwrapValidationValue(rv reflect.Value) (reflect.Value, bool) {
+	if IsPatchField(rv.Type()) {
+		if !rv.FieldByName("Valid").Bool() {
+			return rv, false
+		}
+		rv = rv.FieldByName("Value")
+	}
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return rv, false
+		}
+		rv = rv.Elem()
+	}
+	return rv, true
+}
+
+// unwrapValidationType is the counterpart of unwrapValidationValue on types.
+func unwrapValidationType(t reflect.Type) reflect.Type {
+	if IsPatchField(t) {
+		valueField, _ := t.FieldByName("Value")
+		t = valueField.Type
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// compileValidationDirectives parses the arguments of the validation
+// directives, and puts the fieldValidators into Resolver.Context.
+func compileValidationDirectives(r *owl.Resolver) error {
+	validators := make(fieldValidators)
+	for _, name := range validationDirectives {
+		d := r.GetDirective(name)
+		if d == nil {
+			continue
+		}
+		if isFileType(r.Type) {
+			return fmt.Errorf("directive %s: cannot be used on a file type field", name)
+		}
+		validator, err := compileValidator(r, d)
+		if err != nil {
+			return fmt.Errorf("directive %s: %w", name, err)
+		}
+		validators[name] = validator
+	}
+	if len(validators) > 0 {
+		r.Context = context.WithValue(r.Context, ctxFieldValidators, validators)
+	}
+	return nil
+}
+
+func compileValidator(r *owl.Resolver, d *owl.Directive) (*fieldValidator, error) {
+	typ := unwrapValidationType(r.Type)
+	switch d.Name {
+	case "len", "minlen", "maxlen":
+		return compileLengthValidator(typ, d)
+	}
+
+	if isSliceType(typ) && !isByteSliceType(typ) {
+		typ = typ.Elem()
+		for typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+	}
+	switch d.Name {
+	case "min", "max":
+		return compileRangeValidator(typ, d)
+	case "pattern":
+		return compilePatternValidator(typ, d)
+	case "enum":
+		return compileEnumValidator(r, typ, d)
+	default: // format
+		return compileFormatValidator(typ, d)
+	}
+}
+
+func compileLengthValidator(typ reflect.Type, d *owl.Directive) (*fieldValidator, error) {
+	if len(d.Argv) != 1 {
+		return nil, fmt.Errorf("expect exactly one argument")
+	}
+	limit, err := strconv.Atoi(d.Argv[0])
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("invalid length %q", d.Argv[0])
+	}
+	switch typ.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, typ)
+	}
+
+	var (
+		violated func(n int) bool
+		message  string
+		code     string
+	)
+	switch d.Name {
+	case "len":
+		violated, message, code = func(n int) bool { return n != limit }, "must be", CodeWrongLength
+	case "minlen":
+		violated, message, code = func(n int) bool { return n < limit }, "must be at least", CodeTooShort
+	default: // maxlen
+		violated, message, code = func(n int) bool { return n > limit }, "must be at most", CodeTooLong
+	}
+	return &fieldValidator{
+		onLength: true,
+		check: func(rv reflect.Value) error {
+			n := rv.Len()
+			if rv.Kind() == reflect.String {
+				n = utf8.RuneCountInString(rv.String())
+			}
+			if violated(n) {
+				return NewCodedError(code, map[string]any{d.Name: limit, "length": n},
+					fmt.Errorf("length %d %s %d", n, message, limit))
+			}
+			return nil
+		},
+	}, nil
+}
+
+func compileRangeValidator(typ reflect.Type, d *owl.Directive) (*fieldValidator, error) {
+	if len(d.Argv) != 1 {
+		return nil, fmt.Errorf("expect exactly one argument")
+	}
+	arg := d.Argv[0]
+	isMin := d.Name == "min"
+	message, code := "greater than the maximum", CodeTooBig
+	if isMin {
+		message, code = "less than the minimum", CodeTooSmall
+	}
+
+	// compare returns -1, 0, 1 when the value is less than, equal to, or
+	// greater than the limit.
+	var (
+		compare func(reflect.Value) int
+		bound   any // the parsed limit, a parameter of the error message
+	)
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		limit, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", arg)
+		}
+		compare, bound = func(rv reflect.Value) int { return cmpOrdered(rv.Int(), limit) }, limit
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		limit, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unsigned integer %q", arg)
+		}
+		compare, bound = func(rv reflect.Value) int { return cmpOrdered(rv.Uint(), limit) }, limit
+	case reflect.Float32, reflect.Float64:
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", arg)
+		}
+		compare, bound = func(rv reflect.Value) int { return cmpOrdered(rv.Float(), limit) }, limit
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, typ)
+	}
+
+	return &fieldValidator{
+		check: func(rv reflect.Value) error {
+			if c := compare(rv); (isMin && c < 0) || (!isMin && c > 0) {
+				return NewCodedError(code, map[string]any{d.Name: bound},
+					fmt.Errorf("value is %s %s", message, arg))
+			}
+			return nil
+		},
+	}, nil
+}
+
+func cmpOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compilePatternValidator(typ reflect.Type, d *owl.Directive) (*fieldValidator, error) {
+	if typ.Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, typ)
+	}
+	// Commas in the pattern have been split into multiple args by the parser.
+	pattern := strings.Join(d.Argv, ",")
+	if pattern == "" {
+		return nil, fmt.Errorf("missing pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &fieldValidator{
+		check: func(rv reflect.Value) error {
+			if !re.MatchString(rv.String()) {
+				return NewCodedError(CodePatternMismatch, map[string]any{"pattern": pattern},
+					fmt.Errorf("value does not match pattern %q", pattern))
+			}
+			return nil
+		},
+	}, nil
+}
+
+// compileEnumValidator parses the allowed values, which are separated by "|"
+// or ",", e.g. "enum=asc|desc". The values of non-string types are decoded by
+// the coders, e.g. "enum=1|2|3" for an int field.
+func compileEnumValidator(r *owl.Resolver, typ reflect.Type, d *owl.Directive) (*fieldValidator, error) {
+	if len(d.Argv) == 0 {
+		return nil, fmt.Errorf("missing allowed values")
+	}
+	var literals []string
+	for _, arg := range d.Argv {
+		literals = append(literals, strings.Split(arg, "|")...)
+	}
+	if !typ.Comparable() {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, typ)
+	}
+
+	var adapt AnyStringableAdaptor
+	if coder, ok := r.Context.Value(CtxCustomCoder).(*NamedAnyStringableAdaptor); ok {
+		adapt = coder.Adapt
+	}
+	allowed := make([]any, len(literals))
+	for i, literal := range literals {
+		if typ.Kind() == reflect.String {
+			allowed[i] = reflect.ValueOf(literal).Convert(typ).Interface()
+			continue
+		}
+		rv := reflect.New(typ).Elem()
+		stringable, err := NewStringable(rv, adapt)
+		if err != nil {
+			return nil, err
+		}
+		if err := stringable.FromString(literal); err != nil {
+			return nil, fmt.Errorf("invalid value %q: %w", literal, err)
+		}
+		allowed[i] = rv.Interface()
+	}
+
+	return &fieldValidator{
+		check: func(rv reflect.Value) error {
+			value := rv.Interface()
+			for _, v := range allowed {
+				if v == value {
+					return nil
+				}
+			}
+			return NewCodedError(CodeNotInEnum, map[string]any{"enum": literals},
+				fmt.Errorf("value is not one of %s", strings.Join(literals, ", ")))
+		},
+	}, nil
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// formatCheckers are the supported formats of the "format" directive.
+var formatCheckers = map[string]func(string) bool{
+	"email": func(s string) bool {
+		addr, err := mail.ParseAddress(s)
+		return err == nil && addr.Address == s // no display name
+	},
+	"uuid": uuidPattern.MatchString,
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.Scheme != ""
+	},
+}
+
+func compileFormatValidator(typ reflect.Type, d *owl.Directive) (*fieldValidator, error) {
+	if len(d.Argv) != 1 {
+		return nil, fmt.Errorf("expect exactly one format")
+	}
+	format := d.Argv[0]
+	isValid := formatCheckers[format]
+	if isValid == nil {
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	if typ.Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedType, typ)
+	}
+	return &fieldValidator{
+		check: func(rv reflect.Value) error {
+			if !isValid(rv.String()) {
+				return NewCodedError(CodeInvalidFormat, map[string]any{"format": format},
+					fmt.Errorf("value is not a valid %s", format))
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,171 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type SearchUsersInput struct {
+	Page     int                  `in:"query=page;min=1"`
+	PerPage  *uint8               `in:"query=per_page;min=1;max=100"`
+	Score    patch.Field[float64] `in:"query=score;min=-1.5;max=1.5"`
+	Username string               `in:"query=username;minlen=3;maxlen=8;pattern=^[a-z]{1,3}[0-9_]*$"`
+	Country  string               `in:"query=country;len=2"`
+	Sort     string               `in:"query=sort;enum=asc|desc"`
+	Levels   []int                `in:"query=level;maxlen=3;enum=1,2,3"`
+	Email    string               `in:"query=email;format=email"`
+	IDs      []string             `in:"query=id;format=uuid"`
+	Homepage string               `in:"query=homepage;format=uri"`
+}
+
+func TestDirectiveValidation_Decode(t *testing.T) {
+	co, err := New(SearchUsersInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/users", nil)
+	r.URL.RawQuery = url.Values{
+		"page":     {"1"},
+		"per_page": {"100"},
+		"score":    {"-1.5"},
+		"username": {"ab_1"},
+		"country":  {"中国"},
+		"sort":     {"desc"},
+		"level":    {"1", "3"},
+		"email":    {"alice@example.com"},
+		"id":       {"123e4567-e89b-12d3-a456-426614174000"},
+		"homepage": {"https://example.com"},
+	}.Encode()
+	perPage := uint8(100)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchUsersInput{
+		Page:     1,
+		PerPage:  &perPage,
+		Score:    patch.Field[float64]{Value: -1.5, Valid: true},
+		Username: "ab_1",
+		Country:  "中国",
+		Sort:     "desc",
+		Levels:   []int{1, 3},
+		Email:    "alice@example.com",
+		IDs:      []string{"123e4567-e89b-12d3-a456-426614174000"},
+		Homepage: "https://example.com",
+	}, got)
+
+	// Absent fields are not validated.
+	r.URL.RawQuery = ""
+	got, err = co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &SearchUsersInput{}, got)
+}
+
+func TestDirectiveValidation_Decode_Errors(t *testing.T) {
+	co, err := New(SearchUsersInput{})
+	assert.NoError(t, err)
+
+	for _, c := range []struct {
+		query     string
+		field     string
+		directive string
+		message   string
+	}{
+		{"page=0", "Page", "min", "value is less than the minimum 1"},
+		{"per_page=101", "PerPage", "max", "value is greater than the maximum 100"},
+		{"score=1.6", "Score", "max", "value is greater than the maximum 1.5"},
+		{"username=ab", "Username", "minlen", "length 2 must be at least 3"},
+		{"username=abcdefghi", "Username", "maxlen", "length 9 must be at most 8"},
+		{"username=abcd", "Username", "pattern", `value does not match pattern "^[a-z]{1,3}[0-9_]*$"`},
+		{"country=USA", "Country", "len", "length 3 must be 2"},
+		{"sort=random", "Sort", "enum", "value is not one of asc, desc"},
+		{"level=1&level=2&level=3&level=1", "Levels", "maxlen", "length 4 must be at most 3"},
+		{"level=1&level=4", "Levels", "enum", "element at index 1: value is not one of 1, 2, 3"},
+		{"email=Alice+<alice@example.com>", "Email", "format", `value is not a valid email`},
+		{"id=123e4567-e89b-12d3-a456-426614174000&id=nope", "IDs", "format", `element at index 1: value is not a valid uuid`},
+		{"homepage=/relative", "Homepage", "format", `value is not a valid uri`},
+	} {
+		r, _ := http.NewRequest("GET", "/users?"+c.query, nil)
+		_, err := co.Decode(r)
+		var invalidField *InvalidFieldError
+		if assert.ErrorAs(t, err, &invalidField, c.query) {
+			assert.Equal(t, c.field, invalidField.Field)
+			assert.Equal(t, c.directive, invalidField.Directive)
+			assert.ErrorContains(t, invalidField, c.message)
+		}
+	}
+}
+
+func TestDirectiveValidation_Decode_SecretNotLeaked(t *testing.T) {
+	type TokenInput struct {
+		Token string `in:"auth=bearer;pattern=^[a-z]+$"`
+	}
+	co, err := New(TokenInput{}, WithErrorHandler(ProblemDetailsErrorHandler(ProblemDetailsOptions{})))
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/me", nil)
+	r.Header.Set("Authorization", "Bearer SECRET123")
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	if assert.ErrorAs(t, err, &invalidField) {
+		assert.Equal(t, "pattern", invalidField.Directive)
+		assert.Nil(t, invalidField.Value)
+		assert.NotContains(t, invalidField.Error(), "SECRET123")
+		assert.NotContains(t, invalidField.Params, "value")
+	}
+
+	rw := httptest.NewRecorder()
+	co.GetErrorHandler()(rw, r, err)
+	assert.Equal(t, http.StatusUnprocessableEntity, rw.Code)
+	assert.NotContains(t, rw.Body.String(), "SECRET123")
+}
+
+func TestDirectiveValidation_NewRequest(t *testing.T) {
+	co, err := New(SearchUsersInput{})
+	assert.NoError(t, err)
+
+	_, err = co.NewRequest("GET", "/users", &SearchUsersInput{Page: 2, Sort: "asc"})
+	assert.NoError(t, err)
+
+	_, err = co.NewRequest("GET", "/users", &SearchUsersInput{Page: 2, Sort: "up"})
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "enum", invalidField.Directive)
+}
+
+func TestDirectiveValidation_New_Errors(t *testing.T) {
+	for _, c := range []struct {
+		input   any
+		message string
+	}{
+		{struct {
+			Name string `in:"query=name;min=1"`
+		}{}, "directive min: unsupported type: string"},
+		{struct {
+			Age int `in:"query=age;max=old"`
+		}{}, `directive max: invalid integer "old"`},
+		{struct {
+			Age int `in:"query=age;len=2"`
+		}{}, "directive len: unsupported type: int"},
+		{struct {
+			Name string `in:"query=name;maxlen=-1"`
+		}{}, `directive maxlen: invalid length "-1"`},
+		{struct {
+			Name string `in:"query=name;pattern=[a-z"`
+		}{}, `directive pattern: invalid pattern "[a-z"`},
+		{struct {
+			Level int `in:"query=level;enum=1|two"`
+		}{}, `directive enum: invalid value "two"`},
+		{struct {
+			Name string `in:"query=name;format=phone"`
+		}{}, `directive format: unknown format "phone"`},
+		{struct {
+			Age int `in:"query=age;format=email"`
+		}{}, "directive format: unsupported type: int"},
+	} {
+		_, err := New(c.input)
+		assert.ErrorContains(t, err, c.message)
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ggicci/owl"
+)
+
+// wildcardDirectives are the directives which support wildcard keys. A
+// wildcard key ends with "*", e.g. "X-Meta-*", which matches all the keys with
+// the prefix "X-Meta-". And "*" alone matches all the keys. The values are
+// collected into a map field, whose keys are the matched keys with the prefix
+// stripped. e.g.
+//
+//	type ProxyInput struct {
+//	    Metadata map[string]string   `in:"header=X-Meta-*"`
+//	    Filters  map[string][]string `in:"query=*"`
+//	}
+var wildcardDirectives = []string{"query", "header", "form", "cookie"}
+
+// wildcardPrefix returns the prefix of a wildcard key, and whether the key is
+// a wildcard key.
+func wildcardPrefix(key string) (string, bool) {
+	if strings.HasSuffix(key, "*") {
+		return key[:len(key)-1], true
+	}
+	return "", false
+}
+
+// validateWildcardKeys ensures that the wildcard keys are used on the map
+// fields whose values can be converted from/to a string slice.
+func validateWildcardKeys(r *owl.Resolver) error {
+	for _, name := range wildcardDirectives {
+		d := r.GetDirective(name)
+		if d == nil {
+			continue
+		}
+		for _, key := range d.Argv {
+			prefix, ok := wildcardPrefix(key)
+			if !ok {
+				continue
+			}
+			if strings.Contains(prefix, "*") {
+				return fmt.Errorf("directive %s: invalid wildcard key %q, \"*\" must be the last character", name, key)
+			}
+			if err := validateWildcardType(r.Type); err != nil {
+				return fmt.Errorf("directive %s: wildcard key %q: %w", name, key, err)
+			}
+			if style := r.Context.Value(ctxFieldStyle); style != nil && style.(*fieldStyle).Style == StyleDeepObject {
+				return fmt.Errorf("directive %s: wildcard key %q cannot be used with %s style", name, key, StyleDeepObject)
+			}
+		}
+	}
+	return nil
+}
+
+func validateWildcardType(t reflect.Type) error {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return fmt.Errorf("%w: %v, requires a map with string keys", ErrUnsupportedType, t)
+	}
+	if isFileType(t.Elem()) || !isDeepObjectLeaf(t.Elem()) {
+		return fmt.Errorf("%w: map value type %v", ErrUnsupportedType, t.Elem())
+	}
+	return nil
+}
+
+// extractWildcard collects the values of the keys which have the given prefix
+// into the map field.
+func (e *FormExtractor) extractWildcard(prefix string) error {
+	rv := e.Runtime.Value.Elem()
+	var adapt AnyStringableAdaptor
+	if decoderInfo := e.Runtime.GetCustomCoder(); decoderInfo != nil {
+		adapt = decoderInfo.Adapt
+	}
+	style := e.Runtime.getFieldStyle()
+	transforms := e.Runtime.getFieldTransforms()
+
+	found := false
+	for _, key := range sortedKeys(e.Form.Value) {
+		values := e.Form.Value[key]
+		if !strings.HasPrefix(key, prefix) || len(key) == len(prefix) || len(values) == 0 {
+			continue
+		}
+
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		decoder, err := NewStringSlicable(elem, adapt)
+		if err == nil {
+			err = decoder.FromStringSlice(transforms.apply(style.splitValues(values)))
+		}
+		if err != nil {
+			return &fieldError{key, values, err}
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		rv.SetMapIndex(reflect.ValueOf(key[len(prefix):]).Convert(rv.Type().Key()), elem)
+		found = true
+	}
+
+	if found {
+		e.Runtime.MarkFieldSet(true)
+	}
+	return nil
+}
+
+// encodeWildcard emits every entry of the map field, the keys are prefixed by
+// the given prefix.
+func (e *FormEncoder) encodeWildcard(rtm *DirectiveRuntime, prefix string) error {
+	rv := rtm.Value
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("%w: %v", ErrUnsupportedType, rv.Type())
+	}
+	var adapt AnyStringableAdaptor
+	if encoderInfo := rtm.GetCustomCoder(); encoderInfo != nil {
+		adapt = encoderInfo.Adapt
+	}
+	style := rtm.getFieldStyle()
+	transforms := rtm.getFieldTransforms()
+
+	keys := rv.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, k := range keys {
+		// Map elements are not addressable, make a copy.
+		elem := reflect.New(rv.Type().Elem()).Elem()
+		elem.Set(rv.MapIndex(k))
+		encoder, err := NewStringSlicable(elem, adapt)
+		if err != nil {
+			return err
+		}
+		values, err := encoder.ToStringSlice()
+		if err != nil {
+			return err
+		}
+		e.Setter(prefix+k.String(), style.joinValues(transforms.apply(values)))
+	}
+	rtm.MarkFieldSet(true)
+	return nil
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type PassthroughInput struct {
+	Metadata map[string]string   `in:"header=X-Meta-*"`
+	Filters  map[string][]string `in:"query=*"`
+	Attrs    map[string]int      `in:"form=attr.*"`
+	Prefs    map[string]string   `in:"cookie=pref_*"`
+}
+
+func TestWildcardKeys_Decode(t *testing.T) {
+	co, err := New(PassthroughInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/proxy?status=open&status=closed&owner=ggicci", strings.NewReader("attr.width=10&attr.height=20&attr.=0&name=box"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("X-Meta-Trace-Id", "abc")
+	r.Header.Set("X-Meta-Region", "eu")
+	r.Header.Set("X-Other", "ignored")
+	r.AddCookie(&http.Cookie{Name: "pref_theme", Value: "dark"})
+	r.AddCookie(&http.Cookie{Name: "session", Value: "ignored"})
+
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &PassthroughInput{
+		Metadata: map[string]string{"Trace-Id": "abc", "Region": "eu"},
+		Filters:  map[string][]string{"status": {"open", "closed"}, "owner": {"ggicci"}},
+		Attrs:    map[string]int{"width": 10, "height": 20},
+		Prefs:    map[string]string{"theme": "dark"},
+	}, got)
+}
+
+func TestWildcardKeys_Decode_Absent(t *testing.T) {
+	co, err := New(PassthroughInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/proxy", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &PassthroughInput{}, got)
+}
+
+func TestWildcardKeys_Decode_InvalidValue(t *testing.T) {
+	co, err := New(PassthroughInput{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("POST", "/proxy", strings.NewReader("attr.width=wide"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = co.Decode(r)
+	var invalidField *InvalidFieldError
+	assert.ErrorAs(t, err, &invalidField)
+	assert.Equal(t, "Attrs", invalidField.Field)
+	assert.Equal(t, "attr.width", invalidField.Key)
+	assert.Equal(t, []string{"wide"}, invalidField.Value)
+}
+
+func TestWildcardKeys_Decode_Delimited(t *testing.T) {
+	type Input struct {
+		Filters map[string][]int `in:"query=f.*;explode=false"`
+	}
+	co, err := New(Input{})
+	assert.NoError(t, err)
+
+	r, _ := http.NewRequest("GET", "/proxy?f.ids=1,2&f.ages=3", nil)
+	got, err := co.Decode(r)
+	assert.NoError(t, err)
+	assert.Equal(t, &Input{
+		Filters: map[string][]int{"ids": {1, 2}, "ages": {3}},
+	}, got)
+}
+
+func TestWildcardKeys_NewRequest(t *testing.T) {
+	co, err := New(PassthroughInput{})
+	assert.NoError(t, err)
+
+	req, err := co.NewRequest("POST", "/proxy", &PassthroughInput{
+		Metadata: map[string]string{"trace-id": "abc"},
+		Filters:  map[string][]string{"status": {"open", "closed"}},
+		Attrs:    map[string]int{"width": 10},
+		Prefs:    map[string]string{"theme": "dark"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", req.Header.Get("X-Meta-Trace-Id"))
+	assert.Equal(t, url.Values{"status": {"open", "closed"}}, req.URL.Query())
+	assert.NoError(t, req.ParseForm())
+	assert.Equal(t, "10", req.PostForm.Get("attr.width"))
+	cookie, err := req.Cookie("pref_theme")
+	assert.NoError(t, err)
+	assert.Equal(t, "dark", cookie.Value)
+}
+
+func TestWildcardKeys_Invalid(t *testing.T) {
+	type NotMap struct {
+		Meta string `in:"header=X-Meta-*"`
+	}
+	_, err := New(NotMap{})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.ErrorContains(t, err, "directive header: wildcard key \"X-Meta-*\"")
+
+	type UnsupportedValue struct {
+		Meta map[string]map[string]string `in:"query=*"`
+	}
+	_, err = New(UnsupportedValue{})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	type WildcardInTheMiddle struct {
+		Meta map[string]string `in:"query=a*b*"`
+	}
+	_, err = New(WildcardInTheMiddle{})
+	assert.ErrorContains(t, err, "\"*\" must be the last character")
+}
@@ -0,0 +1,40 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAMLBody is the BodySerializer of the "yaml" body format, which is
+// implemented by gopkg.in/yaml.v3. The struct fields are named by the "yaml"
+// tag, or the lowercased field name.
+type YAMLBody struct{}
+
+func (de *YAMLBody) Decode(src io.Reader, dst any) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	err = yaml.NewDecoder(bytes.NewReader(data)).Decode(dst)
+	var typeError *yaml.TypeError
+	if err == nil || errors.Is(err, io.EOF) || errors.As(err, &typeError) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrMalformedRequest, err) // syntax errors
+}
+
+func (en *YAMLBody) Encode(src any) (io.Reader, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	if err := encoder.Encode(src); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ggicci/httpin/patch"
+	"github.com/stretchr/testify/assert"
+)
+
+type ServiceConfig struct {
+	Name     string                 `yaml:"name"`
+	Replicas int                    `yaml:"replicas"`
+	Ports    []int                  `yaml:"ports"`
+	Deployed time.Time              `yaml:"deployed"`
+	Labels   map[string]string      `yaml:"labels"`
+	Image    patch.Field[string]    `yaml:"image"`
+	Weight   patch.Field[*float64]  `yaml:"weight"`
+	Hosts    patch.Field[[]string]  `yaml:"hosts"`
+	Limits   patch.Field[yamlLimit] `yaml:"limits"`
+}
+
+type yamlLimit struct {
+	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory"`
+}
+
+type ServiceConfigInput struct {
+	Config *ServiceConfig `in:"body=yaml"`
+}
+
+func TestYAML_Body(t *testing.T) {
+	co, err := New(ServiceConfigInput{})
+	assert.NoError(t, err)
+
+	input := &ServiceConfigInput{Config: &ServiceConfig{
+		Name:     "api",
+		Replicas: 3,
+		Ports:    []int{80, 443},
+		Deployed: time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+		Labels:   map[string]string{"tier": "web"},
+		Image:    patch.Field[string]{Value: "api:1.2", Valid: true},
+		Limits:   patch.Field[yamlLimit]{Value: yamlLimit{CPU: "500m", Memory: "1Gi"}, Valid: true},
+	}}
+	req, err := co.NewRequest("PUT", "/config", input)
+	assert.NoError(t, err)
+	assert.Equal(t, "application/yaml", req.Header.Get("Content-Type"))
+
+	got, err := co.Decode(req)
+	assert.NoError(t, err)
+	assert.Equal(t, input, got)
+
+	// The missing and null patch fields are not valid.
+	req, _ = http.NewRequest("PUT", "/config", strings.NewReader("name: api\nimage: ~\nhosts: [a, b]\n"))
+	req.Header.Set("Content-Type", "application/yaml")
+	got, err = co.Decode(req)
+	assert.NoError(t, err)
+	config := got.(*ServiceConfigInput).Config
+	assert.False(t, config.Image.Valid)
+	assert.False(t, config.Weight.Valid)
+	assert.Equal(t, patch.Field[[]string]{Value: []string{"a", "b"}, Valid: true}, config.Hosts)
+}
+
+func TestYAML_Errors(t *testing.T) {
+	co, err := New(ServiceConfigInput{})
+	assert.NoError(t, err)
+
+	for _, c := range []struct {
+		body   string
+		status int
+	}{
+		{"", http.StatusBadRequest},
+		{"name: [api", http.StatusBadRequest},
+		{"replicas: many", http.StatusUnprocessableEntity},
+	} {
+		req, _ := http.NewRequest("PUT", "/config", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/yaml")
+		_, err := co.Decode(req)
+		assert.Error(t, err, c.body)
+		assert.Equal(t, c.status, DefaultErrorStatusMapper(err), c.body)
+	}
+}
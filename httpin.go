@@ -28,6 +28,12 @@ var Option coreOptions = coreOptions{
 	WithErrorHandler:            core.WithErrorHandler,
 	WithMaxMemory:               core.WithMaxMemory,
 	WithNestedDirectivesEnabled: core.WithNestedDirectivesEnabled,
+	WithTrustedProxies:          core.WithTrustedProxies,
+	WithTrustedProxyHeader:      core.WithTrustedProxyHeader,
+	WithBodyReplay:              core.WithBodyReplay,
+	WithCollectAllErrors:        core.WithCollectAllErrors,
+	WithErrorStatusMapper:       core.WithErrorStatusMapper,
+	WithMaxBodyBytes:            core.WithMaxBodyBytes,
 }
 
 // New calls core.New to create a new Core instance. Which is responsible for both:
@@ -56,6 +62,10 @@ func New(inputStruct any, opts ...core.Option) (*core.Core, error) {
 // client side, it is used to represent a file to be uploaded.
 type File = core.File
 
+// Stream is the builtin type of httpin to decode the elements of a streaming
+// body lazily, i.e. `in:"body=ndjson"`, or a JSON array of `in:"body=json"`.
+type Stream[T any] = core.Stream[T]
+
 // UploadFile is a helper function to create a File instance from a file path.
 // It is useful when you want to upload a file from the local file system.
 func UploadFile(path string) *File {
@@ -184,4 +194,28 @@ type coreOptions struct {
 
 	// WithNestedDirectivesEnabled enables/disables nested directives.
 	WithNestedDirectivesEnabled func(bool) core.Option
+
+	// WithTrustedProxies sets the proxies whose forwarding headers are trusted
+	// by the "clientip" directive.
+	WithTrustedProxies func(...string) core.Option
+
+	// WithTrustedProxyHeader sets the forwarding header set by the trusted
+	// proxies, one of Forwarded, X-Forwarded-For (default) and X-Real-IP.
+	WithTrustedProxyHeader func(string) core.Option
+
+	// WithBodyReplay makes the request body replayable, i.e. it can be decoded
+	// by multiple "body" directives and read again by the downstream handlers.
+	WithBodyReplay func(int64) core.Option
+
+	// WithCollectAllErrors makes the decoding continue after a field fails,
+	// and return all the failures as a core.MultiInvalidFieldError.
+	WithCollectAllErrors func(bool) core.Option
+
+	// WithErrorStatusMapper overrides the mapping from the decoding errors to
+	// the HTTP status codes used by the default error handler.
+	WithErrorStatusMapper func(core.ErrorStatusMapper) core.Option
+
+	// WithMaxBodyBytes limits the size of the request body, exceeding it
+	// results in a 413 response by default.
+	WithMaxBodyBytes func(int64) core.Option
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -15,6 +16,7 @@ import (
 var (
 	ErrTypeMismatch    = errors.New("type mismatch")
 	ErrUnsupportedType = owl.ErrUnsupportedType
+	ErrInvalidTime     = errors.New("invalid time value")
 
 	builtinStringableAdaptors = make(map[reflect.Type]AnyStringableAdaptor)
 )
@@ -38,6 +40,7 @@ func init() {
 	builtinStringable[complex128](func(v *complex128) (Stringable, error) { return (*Complex128)(v), nil })
 	builtinStringable[time.Time](func(v *time.Time) (Stringable, error) { return (*Time)(v), nil })
 	builtinStringable[[]byte](func(b *[]byte) (Stringable, error) { return (*ByteSlice)(b), nil })
+	builtinStringable[url.URL](func(v *url.URL) (Stringable, error) { return (*URL)(v), nil })
 }
 
 type StringMarshaler interface {
@@ -344,7 +347,7 @@ func DecodeTime(value string) (time.Time, error) {
 		return DecodeUnixtime(value)
 	}
 
-	return time.Time{}, errors.New("invalid time value")
+	return time.Time{}, ErrInvalidTime
 }
 
 // value must be valid unix timestamp, matches reUnixtime.
@@ -380,6 +383,23 @@ func (bs *ByteSlice) FromString(s string) error {
 	return nil
 }
 
+// URL is a wrapper of url.URL to implement Stringable.
+type URL url.URL
+
+func (uv URL) ToString() (string, error) {
+	u := url.URL(uv)
+	return u.String(), nil
+}
+
+func (uv *URL) FromString(s string) error {
+	v, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	*uv = URL(*v)
+	return nil
+}
+
 func UnsupportedType(rt reflect.Type) error {
 	return fmt.Errorf("%w: %v", ErrUnsupportedType, rt)
 }
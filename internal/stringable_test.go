@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -148,6 +149,20 @@ func TestNewStringable_ByteSlice(t *testing.T) {
 	assert.Error(t, sv.FromString("hello"))
 }
 
+func TestNewStringable_URL(t *testing.T) {
+	var u url.URL
+	rvURLPointer := reflect.ValueOf(&u)
+	sv, err := NewStringable(rvURLPointer)
+	assert.NoError(t, err)
+	assert.NoError(t, sv.FromString("https://example.com/users?page=1#top"))
+	assert.Equal(t, "example.com", u.Host)
+	assert.Equal(t, "/users", u.Path)
+	got, err := sv.ToString()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/users?page=1#top", got)
+	assert.Error(t, sv.FromString("http://[::1"))
+}
+
 func TestNewStringable_ErrUnsupportedType(t *testing.T) {
 	type MyStruct struct{ Name string }
 	var s MyStruct
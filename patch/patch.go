@@ -3,6 +3,8 @@ package patch
 import (
 	"bytes"
 	"encoding/json"
+
+	"gopkg.in/yaml.v3"
 )
 
 // Field is a wrapper which can tell if a field was unmarshalled from the data provided.
@@ -27,3 +29,18 @@ func (f *Field[T]) UnmarshalJSON(data []byte) error {
 	}
 	return err
 }
+
+func (f Field[T]) MarshalYAML() (any, error) {
+	if !f.Valid {
+		return nil, nil
+	}
+	return f.Value, nil
+}
+
+func (f *Field[T]) UnmarshalYAML(value *yaml.Node) error {
+	err := value.Decode(&f.Value)
+	if err == nil && value.ShortTag() != "!!null" {
+		f.Valid = true
+	}
+	return err
+}
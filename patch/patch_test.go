@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ggicci/httpin/patch"
+	"gopkg.in/yaml.v3"
 )
 
 func shouldBeNil(t *testing.T, err error, failMessage string) {
@@ -237,3 +238,24 @@ func TestField_MarshalJSON_Struct(t *testing.T) {
 		testJSONMarshalling(t, c)
 	}
 }
+
+func TestField_YAML(t *testing.T) {
+	type ConfigPatch struct {
+		Name    patch.Field[string]   `yaml:"name"`
+		Ports   patch.Field[[]int]    `yaml:"ports"`
+		Weight  patch.Field[*float64] `yaml:"weight"`
+		Comment patch.Field[string]   `yaml:"comment"`
+	}
+
+	var got ConfigPatch
+	content := "name: api\nports: [80, 443]\nweight: null\n"
+	shouldBeNil(t, yaml.Unmarshal([]byte(content), &got), "unmarshal failed")
+	shouldResemble(t, ConfigPatch{
+		Name:  patch.Field[string]{"api", true},
+		Ports: patch.Field[[]int]{[]int{80, 443}, true},
+	}, got, "unmarshal failed")
+
+	bs, err := yaml.Marshal(got)
+	shouldBeNil(t, err, "marshal failed")
+	shouldResemble(t, "name: api\nports:\n    - 80\n    - 443\nweight: null\ncomment: null\n", string(bs), "marshal failed")
+}